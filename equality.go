@@ -7,6 +7,7 @@ import (
 	"math"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/smartystreets/assertions/internal/go-render/render"
 	"github.com/smartystreets/assertions/internal/oglematchers"
@@ -209,6 +210,31 @@ func ShouldNotResemble(actual any, expected ...any) string {
 	return success
 }
 
+// ShouldEqualRendered receives exactly two parameters and checks that they render
+// identically (see ShouldResemble). Unlike ShouldResemble, the values need not be
+// deeply equal, so differences that don't show when rendered (such as the
+// monotonic clock reading of a time.Time) are ignored.
+func ShouldEqualRendered(actual any, expected ...any) string {
+	if message := need(1, expected); message != success {
+		return message
+	}
+
+	renderedExpected, renderedActual := renderForComparison(expected[0]), renderForComparison(actual)
+	if renderedExpected != renderedActual {
+		message := fmt.Sprintf(shouldHaveRenderedEqually, renderedExpected, renderedActual) +
+			composePrettyDiff(renderedExpected, renderedActual)
+		return serializer.serializeDetailed(expected[0], actual, message)
+	}
+
+	return success
+}
+
+// renderForComparison renders value for ShouldEqualRendered. Times are formatted
+// without their monotonic clock reading, which time.Time's String includes.
+func renderForComparison(value any) string {
+	return render.RenderWith(value, render.RenderOptions{TimeLayout: time.RFC3339Nano})
+}
+
 // ShouldPointTo receives exactly two parameters and checks to see that they point to the same address.
 func ShouldPointTo(actual any, expected ...any) string {
 	if message := need(1, expected); message != success {
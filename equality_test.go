@@ -160,6 +160,24 @@ func (this *AssertionsFixture) TestShouldResemble() {
 	this.fail(so(anyVal{123}, ShouldResemble, anyVal{int64(123)}), "{123}|{123}|Expected: 'assertions.anyVal{val:123}' Actual: 'assertions.anyVal{val:123}' (Should resemble, but there is a type difference within the two)!")
 }
 
+func (this *AssertionsFixture) TestShouldEqualRendered() {
+	this.fail(so(Thing1{"hi"}, ShouldEqualRendered), "This assertion requires exactly 1 comparison values (you provided 0).")
+	this.fail(so(Thing1{"hi"}, ShouldEqualRendered, Thing1{"hi"}, Thing1{"hi"}), "This assertion requires exactly 1 comparison values (you provided 2).")
+
+	this.pass(so(Thing1{"hi"}, ShouldEqualRendered, Thing1{"hi"}))
+	this.fail(so(Thing1{"hi"}, ShouldEqualRendered, Thing1{"bye"}), `{bye}|{hi}|Expected: 'assertions.Thing1{a:"bye"}' Actual: 'assertions.Thing1{a:"hi"}' (Should render equally)! Diff: 'assertions.Thing1{a:"byehi"}'`)
+	this.fail(so(IntAlias(42), ShouldEqualRendered, 42), `42|42|Expected: '42' Actual: 'assertions.IntAlias(42)' (Should render equally)!`)
+
+	type event struct {
+		Name string
+		At   time.Time
+	}
+	now := time.Now()
+	withMonotonic, withoutMonotonic := event{"launch", now}, event{"launch", now.Round(0)}
+	this.pass(so(withMonotonic, ShouldNotResemble, withoutMonotonic)) // The monotonic clock reading prevents ShouldResemble!
+	this.pass(so(withMonotonic, ShouldEqualRendered, withoutMonotonic))
+}
+
 func (this *AssertionsFixture) TestShouldNotResemble() {
 	this.fail(so(Thing1{"hi"}, ShouldNotResemble), "This assertion requires exactly 1 comparison values (you provided 0).")
 	this.fail(so(Thing1{"hi"}, ShouldNotResemble, Thing1{"hi"}, Thing1{"hi"}), "This assertion requires exactly 1 comparison values (you provided 2).")
@@ -6,10 +6,17 @@ package render
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"math"
+	"path"
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var builtinTypeMap = map[reflect.Kind]string{
@@ -44,234 +51,1594 @@ var typeOfString = reflect.TypeOf("")
 var typeOfInt = reflect.TypeOf(int(1))
 var typeOfUint = reflect.TypeOf(uint(1))
 var typeOfFloat = reflect.TypeOf(10.1)
+var typeOfByte = reflect.TypeOf(byte(0))
 
 // Render converts a structure to a string representation. Unline the "%#v"
 // format string, this resolves pointer types' contents in structs, maps, and
 // slices/arrays and prints their field values.
+//
+// Values of named types are rendered with their type name, including named
+// slice, map, and array types, e.g. `render.grid{...}` rather than
+// `[2][3]int{...}`.
 func Render(v any) string {
-	buf := bytes.Buffer{}
-	s := (*traverseState)(nil)
-	s.render(&buf, 0, reflect.ValueOf(v), false)
-	return buf.String()
+	return defaultRenderer.Render(v)
+}
+
+// RenderWith is like Render, but allows the output to be customized through
+// opts.
+func RenderWith(v any, opts RenderOptions) string {
+	return renderValue(reflect.ValueOf(v), &opts)
+}
+
+// RenderValue is like RenderWith, but renders the value held by rv, saving
+// callers working with reflection the conversion to any. An invalid rv renders
+// as "<invalid>".
+//
+// Values obtained through unexported struct fields can be rendered, as though
+// they were reached while rendering their parent.
+func RenderValue(rv reflect.Value, opts RenderOptions) string {
+	if !rv.IsValid() {
+		return "<invalid>"
+	}
+	return renderValue(rv, &opts)
+}
+
+// RenderSafe renders v with SafeRenderOptions, bounding the size of the
+// output and the time spent producing it.
+func RenderSafe(v any) string {
+	return RenderWith(v, SafeRenderOptions())
+}
+
+// renderValue renders rv with opts.
+func renderValue(rv reflect.Value, opts *RenderOptions) string {
+	r := renderer{opts: opts}
+	r.run(rv)
+	return r.buf.String()
+}
+
+// RenderTo writes the rendering of v, customized through opts, to w.
+func RenderTo(w io.Writer, v any, opts RenderOptions) error {
+	if opts.Color && !isTerminal(w) {
+		opts.Color = false
+	}
+	r := defaultRenderer.get(&opts)
+	defer defaultRenderer.put(r)
+	r.run(reflect.ValueOf(v))
+	_, err := w.Write(r.buf.Bytes())
+	return err
+}
+
+// RenderSize returns the length in bytes of RenderWith(v, opts). The value is
+// still rendered in full, since limits such as MaxTotalBytes trim the output
+// after the fact, but into a reused buffer rather than a new string.
+func RenderSize(v any, opts RenderOptions) int {
+	r := defaultRenderer.get(&opts)
+	defer defaultRenderer.put(r)
+	r.run(reflect.ValueOf(v))
+	return r.buf.Len()
+}
+
+// RenderEqual reports whether a and b render identically with Render.
+//
+// Rendering of b stops as soon as it grows longer than the rendering of a, so
+// comparing a small value against a large one is cheap.
+func RenderEqual(a, b any) bool {
+	ra := renderer{opts: &RenderOptions{}}
+	ra.run(reflect.ValueOf(a))
+	rb := renderer{opts: &RenderOptions{MaxTotalBytes: ra.buf.Len()}}
+	rb.run(reflect.ValueOf(b))
+	return !rb.truncated && bytes.Equal(ra.buf.Bytes(), rb.buf.Bytes())
+}
+
+// RenderHighlight is like Render, but wraps the value at path in ">>> " and
+// " <<<" markers, so that it can be spotted in a large rendering.
+//
+// The path is written like those of RenderDiff, e.g. `Users[2].Addr.Zip` or
+// `Tags."key"`. If no value is found at path, v is rendered without markers.
+func RenderHighlight(v any, path string) string {
+	r := renderer{opts: &RenderOptions{}, highlight: path}
+	r.run(reflect.ValueOf(v))
+	return r.buf.String()
+}
+
+// RenderLines renders v in indented form (see RenderOptions.Indent), one
+// struct field, slice or array element, or map entry per line, and returns the
+// individual lines without trailing newlines.
+func RenderLines(v any) []string {
+	return strings.Split(RenderWith(v, RenderOptions{Indent: "\t"}), "\n")
+}
+
+// traverseState is used to note and avoid recursion as struct members are being
+// traversed.
+//
+// traverseState is allowed to be nil. Specifically, the root state is nil.
+type traverseState struct {
+	parent *traverseState
+	ptr    uintptr
+
+	// path is the path at which ptr was seen, if paths are tracked.
+	path string
+
+	// links is the number of consecutive pointers of type ptrType, ending
+	// with ptr, that were followed to get here (see MaxLinkedLen).
+	ptrType reflect.Type
+	links   int
+}
+
+func (s *traverseState) forkFor(ptr uintptr) *traverseState {
+	if s.find(ptr) != nil {
+		return nil
+	}
+
+	fs := &traverseState{
+		parent: s,
+		ptr:    ptr,
+	}
+	return fs
+}
+
+// find returns the state in which ptr was seen, or nil if it wasn't.
+func (s *traverseState) find(ptr uintptr) *traverseState {
+	for cur := s; cur != nil; cur = cur.parent {
+		if ptr == cur.ptr {
+			return cur
+		}
+	}
+	return nil
+}
+
+// renderer holds the state of a single rendering operation.
+type renderer struct {
+	opts *RenderOptions
+	buf  bytes.Buffer
+
+	// truncated is set once the output has exceeded MaxTotalBytes, and
+	// nodeLimited once more than MaxNodes values have been visited.
+	truncated   bool
+	nodeLimited bool
+
+	// ctx, if not nil, is checked periodically while rendering. If it is
+	// done, its error is recorded in err and rendering stops.
+	ctx   context.Context
+	err   error
+	nodes int
+
+	// maxDepth is the deepest nesting of values rendered, for Header.
+	maxDepth int
+
+	// depth is the nesting depth of the elements currently being rendered.
+	depth int
+
+	// forceType is set to render the next value with its type, even if it is
+	// a builtin type that would normally be rendered bare.
+	forceType bool
+
+	// isKey is set to render the next value as a map key, which isn't
+	// reported to OnNode, and is quoted as set by UnquotedKeys.
+	isKey bool
+
+	// elideType is set to render the next value without its type, including
+	// any pointers to it, as its type is implied (see ElideElemTypes).
+	elideType bool
+
+	// pendingAddr is the address of the next value to be rendered, if it is
+	// to be shown (see ShowAddresses), and addr that of the current value. It
+	// is written with the value's pointer type.
+	pendingAddr, addr uintptr
+
+	// keyIDs numbers the keys of the map whose key is being rendered, if it
+	// is keyed by pointers (see PointerKeyIDs). The key's number is written
+	// in place of its address.
+	keyIDs map[uintptr]int
+
+	// defaults is the struct of Defaults, made addressable once so that its
+	// unexported fields can be compared (see isDefault).
+	defaults reflect.Value
+
+	// pathComment is the path of the element whose line is to be ended next,
+	// for PathComments.
+	pathComment string
+
+	// include holds the IncludeFields paths that apply to the next struct
+	// rendered, relative to it. If nil, all of its fields are rendered.
+	include []string
+
+	// highlight is the path of the value to be highlighted, if any, until it
+	// has been rendered (see RenderHighlight), and path that of the value
+	// being rendered. The path is only tracked when needed (see tracksPath).
+	highlight, path string
+
+	// cycleTargets holds the pointers of the values that cycles lead back to,
+	// and cycleIDs the ids assigned to those rendered so far (see CycleIDs).
+	cycleTargets map[uintptr]bool
+	cycleIDs     map[uintptr]int
+
+	// inline is set while a container is rendered on a single line in
+	// indented mode, to see whether it fits (see WrapAt). The OnNode calls
+	// made meanwhile are held in nodeReports until the line is kept.
+	inline      bool
+	nodeReports []nodeReport
+
+	// tokens is set to record the tokens written in spans (see RenderTokens).
+	tokens bool
+	spans  []span
+
+	// open holds the brackets of the containers currently being rendered, and
+	// openAtCut a copy of it taken when the output was truncated. They are
+	// only tracked in indented mode with MaxTotalBytes set, so that truncated
+	// output can be closed off.
+	open      []bracket
+	openAtCut []bracket
+}
+
+// bracket is an opening bracket written at pos in the output, at nesting
+// depth depth, which is closed by close.
+type bracket struct {
+	pos, depth int
+	close      byte
+}
+
+// exhausted returns true if rendering should stop because the output has
+// exceeded MaxTotalBytes, MaxNodes values have been rendered, or the context is
+// done.
+func (r *renderer) exhausted() bool {
+	if !r.truncated && r.opts.MaxTotalBytes > 0 && r.buf.Len() > r.opts.MaxTotalBytes {
+		r.truncate()
+	}
+	return r.truncated || r.nodeLimited || r.err != nil
+}
+
+// truncate marks the output as truncated, noting the brackets that are open.
+func (r *renderer) truncate() {
+	r.truncated = true
+	r.openAtCut = append([]bracket(nil), r.open...)
+}
+
+// tracksBrackets returns true if open brackets need to be tracked.
+func (r *renderer) tracksBrackets() bool {
+	return r.opts.Indent != "" && r.opts.MaxTotalBytes > 0
+}
+
+// openBracket writes the opening bracket open, which is later closed by
+// closeBracket with close.
+func (r *renderer) openBracket(open, close byte) {
+	if r.tracksBrackets() {
+		r.open = append(r.open, bracket{r.buf.Len(), r.depth, close})
+	}
+	r.buf.WriteByte(open)
+}
+
+// closeBracket writes close, closing the most recently opened bracket.
+func (r *renderer) closeBracket(close byte) {
+	if r.tracksBrackets() {
+		// If the closing bracket is going to be cut off, the bracket is still
+		// open as far as the truncated output is concerned.
+		if !r.truncated && r.buf.Len() >= r.opts.MaxTotalBytes {
+			r.truncate()
+		}
+		r.open = r.open[:len(r.open)-1]
+	}
+	r.buf.WriteByte(close)
+}
+
+// visit is called once for each value that is rendered.
+func (r *renderer) visit() {
+	r.nodes++
+	if r.depth >= r.maxDepth {
+		r.maxDepth = r.depth + 1
+	}
+	if r.ctx != nil && r.nodes%ctxCheckInterval == 1 {
+		r.err = r.ctx.Err()
+	}
+	if max := r.opts.MaxNodes; max > 0 && r.nodes > max && !r.exhausted() {
+		r.nodeLimited = true
+		r.writeToken(TokenTruncated, "...<node limit>")
+	}
+}
+
+// run renders v and finalizes the output.
+func (r *renderer) run(v reflect.Value) {
+	if r.opts.Locker != nil {
+		r.opts.Locker.Lock()
+		defer r.opts.Locker.Unlock()
+	}
+	if r.needsAddress(v) {
+		v = addressable(v)
+	}
+	if r.opts.GoSyntax {
+		writeGo(&r.buf, nil, v, true, r.opts)
+		return
+	}
+	if len(r.opts.IncludeFields) > 0 {
+		r.include = r.opts.IncludeFields
+	}
+	if r.opts.CycleIDs {
+		// Find the values that cycles lead back to beforehand, so that they
+		// can be tagged with their ids as they are rendered.
+		dryOpts := *r.opts
+		dryOpts.OnNode = nil
+		dry := renderer{opts: &dryOpts, ctx: r.ctx, include: r.include, cycleTargets: map[uintptr]bool{}}
+		dry.render(nil, 0, v, false)
+		r.cycleTargets, r.cycleIDs = dry.cycleTargets, map[uintptr]int{}
+	}
+	r.render(nil, 0, v, false)
+	if r.opts.ShowMethods && !r.exhausted() {
+		r.writeMethods(v)
+	}
+	r.finish()
+	if r.opts.Header {
+		r.writeHeader(v)
+	}
+}
+
+// writeHeader prepends a comment summarizing the rendered value to the
+// output, e.g. "// *render.T, depth=3, nodes=12".
+func (r *renderer) writeHeader(v reflect.Value) {
+	name := "nil"
+	if v.IsValid() {
+		name = r.typeText(v.Type())
+	}
+	header := fmt.Sprintf("// %s, depth=%d, nodes=%d\n", name, r.maxDepth, r.nodes)
+
+	body := r.takeSpans(0)
+	out := append([]byte(header), r.buf.Bytes()...)
+	r.buf.Reset()
+	r.writeToken(TokenMarker, header[:len(header)-1])
+	r.buf.Write(out[len(header)-1:])
+	r.putSpans(body, len(header))
+}
+
+// writeMethods writes a comment listing the exported methods of v's type, if
+// it has any. The methods of its pointer type are included if v is
+// addressable.
+func (r *renderer) writeMethods(v reflect.Value) {
+	if !v.IsValid() {
+		return
+	}
+	t := v.Type()
+	if v.CanAddr() && t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface {
+		t = reflect.PtrTo(t)
+	}
+	var names []string
+	for i := 0; i < t.NumMethod(); i++ {
+		if m := t.Method(i); m.IsExported() {
+			names = append(names, m.Name)
+		}
+	}
+	if len(names) > 0 {
+		r.buf.WriteByte(' ')
+		r.writeToken(TokenMarker, "/* methods: "+strings.Join(names, ", ")+" */")
+	}
+}
+
+// addressable returns an addressable copy of v, so that values nested within
+// it are addressable as well.
+func addressable(v reflect.Value) reflect.Value {
+	if v.IsValid() && !v.CanAddr() && v.CanInterface() {
+		av := reflect.New(v.Type()).Elem()
+		av.Set(v)
+		return av
+	}
+	return v
+}
+
+// needsAddress returns true if rendering v, which isn't addressable, needs an
+// addressable copy of it. Values nested in v through unexported fields can
+// only be read when addressable (see exportedValue), which only some types and
+// options do, and ShowMethods lists the pointer methods of addressable values.
+func (r *renderer) needsAddress(v reflect.Value) bool {
+	if !v.IsValid() || v.CanAddr() || !v.CanInterface() {
+		return false
+	}
+	return r.opts.ShowMethods || r.readsUnexported(v.Type(), false)
+}
+
+// readsUnexported returns true if values of type t hold values that are read
+// through unexported fields when rendered, without indirection. hidden is set
+// if t itself was reached through an unexported field.
+func (r *renderer) readsUnexported(t reflect.Type, hidden bool) bool {
+	if hidden {
+		switch t {
+		case timeType:
+			return r.opts.UnexportedTimes
+		case typeOfError:
+			return r.opts.Errors
+		case typeOfIP, typeOfIPNet, typeOfAddr, typeOfAddrPort, typeOfPrefix, typeOfReflectType:
+			return true
+		}
+		if r.opts.Defaults != nil || t.PkgPath() == "sync/atomic" {
+			return true
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); r.readsUnexported(f.Type, hidden || !f.IsExported()) {
+				return true
+			}
+		}
+	case reflect.Array:
+		return r.readsUnexported(t.Elem(), hidden)
+	}
+	return false
 }
 
-// renderPointer is called to render a pointer value.
+// finish finalizes the rendered output, trimming it to MaxTotalBytes and
+// marking it if it was truncated.
+func (r *renderer) finish() {
+	if r.exhausted(); !r.truncated {
+		return
+	}
+	if cut := r.opts.MaxTotalBytes; r.buf.Len() > cut {
+		for cut > 0 && !utf8.RuneStart(r.buf.Bytes()[cut]) {
+			cut--
+		}
+		if r.opts.Color {
+			cut = trimPartialEscape(r.buf.Bytes()[:cut])
+		}
+		r.buf.Truncate(cut)
+		r.cutSpans(cut)
+	}
+	kept := r.buf.Len()
+	r.endColor()
+	r.writeToken(TokenTruncated, "...<truncated>")
+
+	// In indented mode, close the brackets that were open at the cut so that
+	// the output stays balanced.
+	for i := len(r.openAtCut) - 1; i >= 0; i-- {
+		if b := r.openAtCut[i]; b.pos < kept {
+			if b.close == '}' {
+				r.newline(b.depth)
+			}
+			r.buf.WriteByte(b.close)
+		}
+	}
+}
+
+// render renders v into the renderer's buffer.
 //
-// This is overridable so that the test suite can have deterministic pointer
-// values in its expectations.
-var renderPointer = func(buf *bytes.Buffer, p uintptr) {
-	fmt.Fprintf(buf, "0x%016x", p)
+// If rendering v panics (e.g., in a user-defined String method), whatever was
+// written for v is discarded and replaced with a "<PANIC: ...>" marker, and
+// rendering continues with v's siblings.
+func (r *renderer) render(s *traverseState, ptrs int, v reflect.Value, implicit bool) {
+	mark, depth, open, include := r.buf.Len(), r.depth, len(r.open), r.include
+	inline, reports := r.inline, len(r.nodeReports)
+	defer func() {
+		if p := recover(); p != nil {
+			r.buf.Truncate(mark)
+			r.cutSpans(mark)
+			r.depth, r.open, r.include = depth, r.open[:open], include
+			r.inline, r.nodeReports = inline, r.nodeReports[:reports]
+			r.writeToken(TokenMarker, fmt.Sprintf("<PANIC: %v>", p))
+		}
+	}()
+	if r.opts.OnNode != nil && !r.isKey {
+		r.reportNode(r.path, v)
+	}
+	if r.highlight != "" && r.path == r.highlight {
+		r.highlight = ""
+		r.writeToken(TokenMarker, ">>>")
+		r.buf.WriteByte(' ')
+		r.renderFitting(s, ptrs, v, implicit)
+		r.buf.WriteByte(' ')
+		r.writeToken(TokenMarker, "<<<")
+		return
+	}
+	r.renderFitting(s, ptrs, v, implicit)
+}
+
+// nodeReport is a call to OnNode, held back while its value is rendered
+// tentatively (see renderFitting).
+type nodeReport struct {
+	path  string
+	value reflect.Value
+}
+
+// reportNode calls OnNode for the value at path, or holds the call back until
+// the tentative line it is part of is kept.
+func (r *renderer) reportNode(path string, v reflect.Value) {
+	if r.inline {
+		r.nodeReports = append(r.nodeReports, nodeReport{path, v})
+		return
+	}
+	r.opts.OnNode(path, v)
+}
+
+// renderFitting is like renderValue. If WrapAt is set in indented mode, the
+// container v is first rendered on a single line, which is kept if it ends
+// within WrapAt columns. Otherwise, it is rendered again, one element per
+// line, each of its elements getting the same chance.
+func (r *renderer) renderFitting(s *traverseState, ptrs int, v reflect.Value, implicit bool) {
+	if r.opts.WrapAt <= 0 || r.opts.Indent == "" || r.inline || !isContainer(v.Kind()) {
+		r.renderValue(s, ptrs, v, implicit)
+		return
+	}
+
+	mark := r.buf.Len()
+	nodes, maxDepth, highlight, pathComment := r.nodes, r.maxDepth, r.highlight, r.pathComment
+	forceType, isKey, elideType, pendingAddr := r.forceType, r.isKey, r.elideType, r.pendingAddr
+	var cycleIDs map[uintptr]int
+	if r.cycleIDs != nil {
+		cycleIDs = make(map[uintptr]int, len(r.cycleIDs))
+		for p, id := range r.cycleIDs {
+			cycleIDs[p] = id
+		}
+	}
+
+	r.inline = true
+	r.renderValue(s, ptrs, v, implicit)
+	r.inline = false
+	reports := r.nodeReports
+	r.nodeReports = nil
+
+	line := r.buf.Bytes()[bytes.LastIndexByte(r.buf.Bytes()[:mark], '\n')+1:]
+	if r.exhausted() || bytes.IndexByte(line, '\n') < 0 && utf8.RuneCount(line) <= r.opts.WrapAt {
+		for _, rep := range reports {
+			r.opts.OnNode(rep.path, rep.value)
+		}
+		return
+	}
+
+	r.buf.Truncate(mark)
+	r.cutSpans(mark)
+	r.nodes, r.maxDepth, r.highlight, r.pathComment = nodes, maxDepth, highlight, pathComment
+	r.forceType, r.isKey, r.elideType, r.pendingAddr = forceType, isKey, elideType, pendingAddr
+	if cycleIDs != nil {
+		r.cycleIDs = cycleIDs
+	}
+	r.renderValue(s, ptrs, v, implicit)
+}
+
+// tracksPath returns true if the path of the value being rendered is needed,
+// for a pending highlight, FieldFilter, RecursionPaths, or OnNode.
+func (r *renderer) tracksPath() bool {
+	return r.highlight != "" || r.opts.FieldFilter != nil || r.opts.RecursionPaths || r.opts.OnNode != nil ||
+		r.opts.PathComments && r.opts.Indent != ""
+}
+
+// reportElems calls OnNode for the elements of the slice or array v from index
+// from up to to, which are left out of the output.
+func (r *renderer) reportElems(v reflect.Value, from, to int) {
+	if r.opts.OnNode == nil {
+		return
+	}
+	for i := from; i < to; i++ {
+		parent := r.enterIndex(i)
+		r.reportNode(r.path, v.Index(i))
+		r.path = parent
+	}
+}
+
+// reportEntries is like reportElems, for the values of map entries.
+func (r *renderer) reportEntries(keys, values []reflect.Value) {
+	if r.opts.OnNode == nil {
+		return
+	}
+	for i, k := range keys {
+		parent := r.enterPath(r.pathKey(k))
+		r.reportNode(r.path, values[i])
+		r.path = parent
+	}
+}
+
+// enterPath sets the path of the value being rendered to that of its field or
+// map entry name, if it is tracked. It returns the path to be
+// restored once the field has been rendered.
+func (r *renderer) enterPath(name string) string {
+	parent := r.path
+	if r.tracksPath() {
+		r.path = joinPath(parent, name)
+	}
+	return parent
+}
+
+// pathKey returns the map key k as rendered in paths. Only the pointer hook
+// of the renderer's options applies, so that paths don't depend on them.
+func (r *renderer) pathKey(k reflect.Value) string {
+	return renderedKey(k, &RenderOptions{formatPointer: r.opts.formatPointer})
+}
+
+// enterIndex is like enterPath, for the element at index i.
+func (r *renderer) enterIndex(i int) string {
+	parent := r.path
+	if r.tracksPath() {
+		r.path = parent + "[" + strconv.Itoa(i) + "]"
+	}
+	return parent
+}
+
+func (r *renderer) renderValue(s *traverseState, ptrs int, v reflect.Value, implicit bool) {
+	buf := &r.buf
+	forceType, elide, isKey := r.forceType, r.elideType, r.isKey
+	r.forceType, r.elideType, r.isKey = false, false, false
+	if elide {
+		implicit = true
+	}
+	r.addr, r.pendingAddr = r.pendingAddr, 0
+	if r.visit(); r.exhausted() {
+		return
+	}
+	if v.Kind() == reflect.Invalid {
+		r.writeNil(false)
+		return
+	}
+	vt := v.Type()
+
+	compact := r.opts.OmitTypePrefix || r.opts.TypePrefixKinds != nil && !r.opts.TypePrefixKinds[v.Kind()]
+	if compact {
+		implicit = true
+	}
+
+	if text, ok := r.opts.OpaqueTypes[vt]; ok {
+		r.writeWrappedToken(ptrs, vt, implicit, text, TokenMarker)
+		return
+	}
+	if vt == typeOfReflectValue && v.CanInterface() {
+		r.renderReflectValue(s, ptrs, v, implicit)
+		return
+	}
+	if text, ok := reflectTypeText(v); ok {
+		r.writeWrappedToken(ptrs, typeOfReflectType, implicit, text, TokenType)
+		return
+	}
+	if handler, ok := r.opts.TypeHandlers[vt]; ok && v.CanInterface() {
+		r.writeWrapped(ptrs, vt, implicit, handler(v.Interface()))
+		return
+	}
+	if name, ok := r.enumName(v); ok {
+		r.writeWrapped(ptrs, vt, implicit, name)
+		return
+	}
+	if names, ok := r.flagNames(v); ok {
+		r.writeWrapped(ptrs, vt, implicit, names)
+		return
+	}
+	if text, ok := r.durationText(v); ok {
+		r.writeWrapped(ptrs, vt, implicit, text)
+		return
+	}
+	if text, ok := calendarText(v); ok {
+		r.writeWrapped(ptrs, vt, implicit, text)
+		return
+	}
+	if text, ok := fileModeText(v); ok {
+		r.writeWrapped(ptrs, vt, implicit, text)
+		return
+	}
+	if text, ok := r.methodText(v); ok {
+		r.writeWrapped(ptrs, vt, implicit, text)
+		return
+	}
+	if text, ok := netText(v); ok {
+		r.writeWrapped(ptrs, vt, implicit, text)
+		return
+	}
+	if text, ok := r.uuidText(v); ok {
+		r.writeWrapped(ptrs, vt, implicit, text)
+		return
+	}
+
+	// If the type being rendered is a potentially recursive type (a type that
+	// can contain itself as a member), we need to avoid recursion.
+	//
+	// If we've already seen this type before, mark that this is the case and
+	// write a recursion placeholder instead of actually rendering it.
+	//
+	// If we haven't seen it before, fork our `seen` tracking so any higher-up
+	// renderers will also render it at least once, then mark that we've seen it
+	// to avoid recursing on lower layers.
+	vk := vt.Kind()
+	if pe := recursionPointer(v); pe != 0 {
+		parent := s
+		s = s.forkFor(pe)
+		if s == nil {
+			r.startColor(colorRecursion)
+			defer r.endColor()
+			defer r.emit(TokenMarker, buf.Len())
+			if r.opts.CycleIDs {
+				r.cycleTargets[pe] = true
+				fmt.Fprintf(buf, "<CYCLE #%d>", r.cycleIDs[pe])
+				return
+			}
+			if r.opts.RecursionPaths {
+				buf.WriteString("<REC -> .")
+				buf.WriteString(parent.find(pe).path)
+				buf.WriteString(">")
+				return
+			}
+			if compact {
+				buf.WriteString("<REC>")
+				return
+			}
+			buf.WriteString("<REC(")
+			if r.opts.RecursionKinds {
+				kind := vk
+				if ptrs > 0 {
+					kind = reflect.Ptr
+				}
+				buf.WriteString(kind.String())
+				if !implicit {
+					buf.WriteString(", ")
+				}
+			}
+			if !implicit {
+				// The marker's parentheses already enclose the type, so the
+				// outer pointers are written without their own.
+				buf.WriteString(strings.Repeat("*", ptrs))
+				r.writeTypeName(0, vt)
+			}
+			buf.WriteString(")>")
+			return
+		}
+		s.path = r.path
+		if vk == reflect.Ptr {
+			s.ptrType, s.links = vt, 1
+			if parent != nil && parent.ptrType == vt {
+				s.links = parent.links + 1
+			}
+			if max := r.opts.MaxLinkedLen; max > 0 && s.links > max {
+				r.writeToken(TokenTruncated, "...(chain continues)")
+				return
+			}
+		}
+		if r.cycleIDs != nil && r.cycleTargets[pe] {
+			if _, ok := r.cycleIDs[pe]; !ok {
+				r.cycleIDs[pe] = len(r.cycleIDs) + 1
+				r.writeToken(TokenMarker, "#"+strconv.Itoa(r.cycleIDs[pe]))
+				buf.WriteByte(' ')
+			}
+		}
+	}
+
+	if max := r.opts.MaxDepth; max > 0 && r.depth >= max && isContainer(vk) && !isNilValue(v) && vt != timeType {
+		if !implicit {
+			r.writeType(ptrs, vt)
+		}
+		buf.WriteByte('{')
+		r.writeToken(TokenTruncated, "...")
+		buf.WriteByte('}')
+		return
+	}
+
+	switch vk {
+	case reflect.Struct:
+		if r.opts.SQLNulls && r.renderSQLNull(s, ptrs, v, implicit) {
+			return
+		}
+		if r.renderAtomic(s, ptrs, v, implicit) {
+			return
+		}
+		if r.opts.InlineWrappers && r.renderWrapper(s, ptrs, v, implicit) {
+			return
+		}
+		if !implicit {
+			r.writeType(ptrs, vt)
+		}
+		r.openBracket('{', '}')
+		if rendered, ok := r.renderTime(v); ok {
+			r.writeToken(TokenLiteral, rendered)
+		} else {
+			written, more, omitted := 0, 0, false
+			include := r.include
+			r.depth++
+			for _, f := range r.structFields(v) {
+				if r.exhausted() {
+					break
+				}
+				fieldInclude, ok := includedField(include, f.name)
+				if !ok {
+					omitted = true
+					continue
+				}
+				if r.skipsField(vt, f) {
+					continue
+				}
+				if n := r.opts.MaxFields; n > 0 && written == n {
+					more++
+					if r.opts.OnNode != nil {
+						parent := r.enterPath(f.name)
+						r.reportNode(r.path, f.value)
+						r.path = parent
+					}
+					continue
+				}
+
+				r.beginElem(written)
+				written++
+
+				if !f.anon {
+					r.writeToken(TokenKey, f.name)
+					if r.opts.ShowTags && f.field.Tag != "" {
+						buf.WriteRune('(')
+						r.writeToken(TokenLiteral, string(f.field.Tag))
+						buf.WriteRune(')')
+					}
+					buf.WriteString(r.kvSep())
+				}
+
+				if r.opts.Redact && hasTagOption(f.field, "redact") {
+					r.writeRedacted(f.value)
+					continue
+				}
+				if r.opts.Errors && f.field.Anonymous && f.field.Type == typeOfError {
+					// Embedded errors are unexported; read them when possible so
+					// that they are rendered by their message.
+					f.value, _ = exportedValue(f.value)
+				}
+				r.include = fieldInclude
+				parent := r.enterPath(f.name)
+				r.render(s, 0, f.value, f.anon)
+				r.notePath()
+				r.include, r.path = include, parent
+			}
+			if more > 0 && !r.exhausted() {
+				r.beginElem(written)
+				written++
+				r.writeToken(TokenTruncated, fmt.Sprintf("...(+%d more fields)", more))
+			}
+			if omitted && !r.exhausted() {
+				r.beginElem(written)
+				written++
+				r.writeToken(TokenMarker, "...")
+			}
+			r.depth--
+			r.endElems(written)
+		}
+		r.closeBracket('}')
+
+	case reflect.Slice:
+		if v.IsNil() {
+			if !implicit {
+				r.writeType(ptrs, vt)
+				r.writeNil(true)
+			} else {
+				r.writeNil(false)
+			}
+			return
+		}
+		if r.opts.BytesAsString && vt.Elem().Kind() == reflect.Uint8 && v.Len() > 0 {
+			r.renderBytes(ptrs, v, implicit)
+			r.writeCap(v)
+			return
+		}
+		fallthrough
+
+	case reflect.Array:
+		if !implicit {
+			r.writeType(ptrs, vt)
+		}
+		anon := vt.Name() == "" && isAnonType(vt.Elem())
+		elide := r.elidesElemType(vt)
+		r.openBracket('{', '}')
+		r.depth++
+		written := 0
+		if r.opts.MinRunLength > 0 {
+			written = r.renderRuns(s, v, anon)
+		} else {
+			for i := 0; i < v.Len(); i++ {
+				if r.exhausted() {
+					break
+				}
+				r.beginElem(written)
+				written++
+
+				if n := r.opts.HeadTail; n > 0 && i == n && v.Len() > 2*n {
+					r.writeMore(v.Len()-2*n, v.Len())
+					r.reportElems(v, n, v.Len()-n)
+					i = v.Len() - n - 1
+					continue
+				}
+				if n := r.opts.MaxSliceLen; n > 0 && i == n && r.opts.HeadTail <= 0 {
+					r.writeMore(v.Len()-n, v.Len())
+					r.reportElems(v, n, v.Len())
+					break
+				}
+				r.writeIndex(i)
+				r.elideType = elide
+				parent := r.enterIndex(i)
+				r.render(s, 0, v.Index(i), anon)
+				r.notePath()
+				r.path = parent
+			}
+		}
+		r.depth--
+		r.endElems(written)
+		r.closeBracket('}')
+		if vk == reflect.Slice {
+			r.writeCap(v)
+		}
+		if r.opts.CommonElemTypes && !r.exhausted() {
+			r.writeCommonElemType(v)
+		}
+
+	case reflect.Map:
+		if !implicit {
+			r.writeType(ptrs, vt)
+		}
+		if v.IsNil() {
+			if implicit {
+				r.writeNil(false)
+			} else {
+				r.writeNil(true)
+			}
+		} else {
+			r.openBracket('{', '}')
+
+			mkeys, mvals := r.mapEntries(v)
+
+			kt := vt.Key()
+			keyAnon := typeOfString.ConvertibleTo(kt) || typeOfInt.ConvertibleTo(kt) || typeOfUint.ConvertibleTo(kt) || typeOfFloat.ConvertibleTo(kt)
+			if r.opts.QualifyMapKeyTypes && !isAnonType(kt) {
+				keyAnon = false
+			}
+			valAnon := vt.Name() == "" && isAnonType(vt.Elem())
+			var keyIDs map[uintptr]int
+			if r.opts.PointerKeyIDs && kt.Kind() == reflect.Ptr {
+				keyIDs = pointerIDs(mkeys)
+			}
+			r.depth++
+			written := 0
+			for i, mk := range mkeys {
+				if r.exhausted() {
+					break
+				}
+				r.beginElem(i)
+				written++
+
+				if n := r.opts.MaxMapLen; n > 0 && i == n {
+					r.writeMore(len(mkeys)-n, len(mkeys))
+					r.reportEntries(mkeys[n:], mvals[n:])
+					break
+				}
+				key, val, anon := mk, mvals[i], valAnon
+				var chain []reflect.Value
+				if r.opts.CompressMapPaths && kt.Kind() == reflect.String {
+					var holder reflect.Type
+					if chain, val, holder = compressMapPath(v, val); len(chain) > 0 {
+						text := mk.String()
+						for _, ck := range chain {
+							text += "." + ck.String()
+						}
+						key = reflect.ValueOf(text).Convert(kt)
+						anon = holder.Name() == "" && isAnonType(holder.Elem())
+					}
+				}
+				r.isKey, r.keyIDs = true, keyIDs
+				keyStart := buf.Len()
+				r.render(s, 0, key, keyAnon)
+				r.keyIDs = nil
+				r.shortenKey(keyStart)
+				buf.WriteString(r.kvSep())
+				parent := r.path
+				if r.tracksPath() {
+					r.enterPath(r.pathKey(mk))
+					for _, ck := range chain {
+						r.enterPath(r.pathKey(ck))
+					}
+				}
+				r.render(s, 0, val, anon)
+				r.notePath()
+				r.path = parent
+			}
+			r.depth--
+			r.endElems(written)
+			r.closeBracket('}')
+		}
+
+	case reflect.Ptr:
+		ptrs++
+		fallthrough
+	case reflect.Interface:
+		if v.IsNil() {
+			if compact || elide {
+				r.writeNil(false)
+				return
+			}
+			r.writeType(ptrs, v.Type())
+			r.writeNil(true)
+		} else if vk == reflect.Interface && r.opts.MarkTypedNils && isNilValue(v.Elem()) {
+			// A non-nil interface holding a nil value.
+			r.writeType(ptrs, vt)
+			r.openBracket('(', ')')
+			r.render(s, 0, v.Elem(), false)
+			buf.WriteByte(' ')
+			r.writeToken(TokenMarker, "<TYPED-NIL>")
+			r.closeBracket(')')
+		} else if vk == reflect.Interface && r.opts.ShowInterfaceTypes && !compact {
+			r.writeType(ptrs, vt)
+			r.openBracket('(', ')')
+			r.render(s, 0, v.Elem(), false)
+			r.closeBracket(')')
+		} else if vk == reflect.Interface && ptrs > 0 && !compact {
+			// A pointer to an interface. The pointers apply to the interface
+			// type rather than to the dynamic type of its value.
+			r.writeType(ptrs, vt)
+			r.openBracket('(', ')')
+			r.forceType = r.opts.ShowDynamicTypes
+			r.render(s, 0, v.Elem(), false)
+			r.closeBracket(')')
+		} else {
+			r.forceType = vk == reflect.Interface && r.opts.ShowDynamicTypes
+			if vk == reflect.Ptr && (r.opts.ShowAddresses || isKey && r.keyIDs != nil) {
+				// Of several pointers, the outermost one's address is shown.
+				if r.addr == 0 {
+					r.addr = v.Pointer()
+				}
+				r.pendingAddr = r.addr
+			}
+			r.elideType = vk == reflect.Ptr && elide
+			r.render(s, ptrs, v.Elem(), false)
+		}
+
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if compact {
+			r.writePointer(v)
+			return
+		}
+		if vk == reflect.Chan && r.opts.ChanCapacity {
+			start := buf.Len()
+			buf.WriteRune('(')
+			buf.WriteString(strings.Repeat("*", ptrs))
+			buf.WriteString(vt.String())
+			fmt.Fprintf(buf, ", cap=%d)", v.Cap())
+			r.emit(TokenType, start)
+		} else {
+			r.writeType(ptrs, vt)
+		}
+		buf.WriteRune('(')
+		r.writePointer(v)
+		buf.WriteRune(')')
+
+	default:
+		tstr := vt.String()
+		builtin := !forceType && ptrs == 0 && builtinTypeMap[vk] == tstr
+		if builtin && r.opts.ShowNumericKinds && vk != reflect.String && vk != reflect.Bool {
+			builtin = false
+		}
+		if vk == reflect.Uintptr && !compact {
+			// uintptr values are address-like, so tag them with their type to
+			// distinguish them from regular integers, unless their container's
+			// type implies it.
+			builtin = false
+		}
+		implicit = implicit || builtin
+		// Builtin complex numbers are written in parentheses of their own,
+		// which double as those following their type.
+		builtinComplex := (vk == reflect.Complex64 || vk == reflect.Complex128) && builtinTypeMap[vk] == tstr
+		if builtinComplex && isKey && r.opts.QualifyMapKeyTypes && !compact && !elide {
+			implicit = false
+		}
+		if !implicit {
+			r.writeType(ptrs, vt)
+			if !builtinComplex {
+				buf.WriteRune('(')
+			}
+		}
+
+		start, cut := buf.Len(), false
+		switch vk {
+		case reflect.String:
+			r.startColor(colorString)
+			str := v.String()
+			if n := r.opts.MaxStringLen; n > 0 && utf8.RuneCountInString(str) > n {
+				str, cut = cutRunes(str, n), true
+			}
+			switch {
+			case r.unquoted(isKey) && isBareString(str):
+				buf.WriteString(str)
+			case r.opts.StringEscaper != nil:
+				buf.WriteString(strconv.Quote(r.opts.StringEscaper(str)))
+			default:
+				fmt.Fprintf(buf, "%q", str)
+			}
+			r.endColor()
+		case reflect.Bool:
+			buf.WriteString(r.boolToken(v.Bool()))
+
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			fmt.Fprintf(buf, "%d", v.Int())
+
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			fmt.Fprintf(buf, "%d", v.Uint())
+
+		case reflect.Uintptr:
+			fmt.Fprintf(buf, "0x%x", v.Uint())
+
+		case reflect.Float32, reflect.Float64:
+			buf.WriteString(r.formatFloat(v.Float()))
+
+		case reflect.Complex64, reflect.Complex128:
+			c := v.Complex()
+			buf.WriteRune('(')
+			buf.WriteString(r.formatFloat(real(c)))
+			if im := r.formatFloat(imag(c)); im[0] == '-' || im[0] == '+' {
+				buf.WriteString(im)
+			} else {
+				buf.WriteRune('+')
+				buf.WriteString(im)
+			}
+			buf.WriteString("i)")
+		}
+		r.emit(scalarToken(vk), start)
+		if cut {
+			r.writeToken(TokenTruncated, "...")
+		}
+
+		if !implicit && !builtinComplex {
+			buf.WriteRune(')')
+		}
+	}
+}
+
+// scalarToken returns the kind of token that scalars of kind k are.
+func scalarToken(k reflect.Kind) TokenKind {
+	switch k {
+	case reflect.String:
+		return TokenString
+	case reflect.Bool:
+		return TokenLiteral
+	}
+	return TokenNumber
+}
+
+// cutRunes returns the first n runes of s.
+func cutRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// unquoted reports whether strings are to be written without quotes, as map
+// keys if isKey is set, and as other values otherwise.
+func (r *renderer) unquoted(isKey bool) bool {
+	if isKey {
+		return r.opts.UnquotedKeys
+	}
+	return r.opts.UnquotedValues
+}
+
+// isBareString reports whether s can be written without quotes and still be
+// told apart from other values: it must be non-empty, consist of letters,
+// digits, and any of "_-./@+", and not read as a number or literal.
+func isBareString(s string) bool {
+	if s == "" || s == "nil" || s == "true" || s == "false" {
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+	for _, c := range s {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune("_-./@+", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatFloat formats f as set by FloatFormat, FloatPrecision, and NoExponent.
+func (r *renderer) formatFloat(f float64) string {
+	format, prec := byte('g'), -1
+	if r.opts.NoExponent {
+		format = 'f'
+	}
+	if r.opts.FloatFormat != 0 {
+		format = r.opts.FloatFormat
+	}
+	if r.opts.FloatPrecision > 0 {
+		prec = r.opts.FloatPrecision
+	}
+	return strconv.FormatFloat(f, format, prec, 64)
+}
+
+// writePointer writes the address held by the channel, func, or unsafe pointer
+// v, or "nil". Funcs may be written by name instead (see FuncNames).
+func (r *renderer) writePointer(v reflect.Value) {
+	if v.IsNil() {
+		r.writeNil(false)
+	} else if name, ok := r.funcName(v); ok {
+		r.writeToken(TokenLiteral, name)
+	} else {
+		start := r.buf.Len()
+		r.writeAddress(v.Pointer())
+		r.emit(TokenNumber, start)
+	}
+}
+
+// recursionPointer returns the pointer identifying v for the purpose of
+// recursion detection, or 0 if v can't recurse. Any value reached through a
+// pointer may lead back to it, e.g. an interface holding a pointer to itself,
+// as may slices and maps.
+func recursionPointer(v reflect.Value) uintptr {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		return v.Pointer()
+	}
+	return 0
+}
+
+// isAnonType returns true if values of type t can be rendered without their
+// type when it is implied by their container's type.
+func isAnonType(t reflect.Type) bool {
+	if t.Name() != "" {
+		if _, ok := builtinTypeSet[t.Name()]; !ok {
+			return false
+		}
+	}
+	return t.Kind() != reflect.Interface
+}
+
+// enumName returns the name registered in EnumNames for v, if v is an integer.
+func (r *renderer) enumName(v reflect.Value) (string, bool) {
+	names, ok := r.opts.EnumNames[v.Type()]
+	if !ok {
+		return "", false
+	}
+	n, ok := intValue(v)
+	if !ok {
+		return "", false
+	}
+	name, ok := names[n]
+	return name, ok
+}
+
+// flagNames returns the names registered in FlagNames for the bits set in v,
+// if v is an integer, e.g. "Read|Write|0x10".
+func (r *renderer) flagNames(v reflect.Value) (string, bool) {
+	names, ok := r.opts.FlagNames[v.Type()]
+	if !ok {
+		return "", false
+	}
+	n, ok := intValue(v)
+	if !ok {
+		return "", false
+	}
+	if n == 0 {
+		if name, ok := names[0]; ok {
+			return name, true
+		}
+		return "0", true
+	}
+
+	flags := make([]int64, 0, len(names))
+	for flag := range names {
+		if flag != 0 {
+			flags = append(flags, flag)
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool { return uint64(flags[i]) < uint64(flags[j]) })
+
+	var set []string
+	rest := uint64(n)
+	for _, flag := range flags {
+		if bits := uint64(flag); rest&bits == bits {
+			set = append(set, names[flag])
+			rest &^= bits
+		}
+	}
+	if rest != 0 {
+		set = append(set, fmt.Sprintf("0x%x", rest))
+	}
+	return strings.Join(set, "|"), true
 }
 
-// traverseState is used to note and avoid recursion as struct members are being
-// traversed.
-//
-// traverseState is allowed to be nil. Specifically, the root state is nil.
-type traverseState struct {
-	parent *traverseState
-	ptr    uintptr
+// intValue returns the value of v if it is an integer representable as an
+// int64.
+func intValue(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() <= math.MaxInt64 {
+			return int64(v.Uint()), true
+		}
+	}
+	return 0, false
 }
 
-func (s *traverseState) forkFor(ptr uintptr) *traverseState {
-	for cur := s; cur != nil; cur = cur.parent {
-		if ptr == cur.ptr {
-			return nil
-		}
+// isNilValue returns true if v is of a nillable kind and is nil.
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface, reflect.UnsafePointer:
+		return v.IsNil()
 	}
+	return false
+}
 
-	fs := &traverseState{
-		parent: s,
-		ptr:    ptr,
+// writeWrapped writes text verbatim, wrapped with the type t unless implicit is
+// set. The text is a string token if it is quoted, and a literal otherwise.
+func (r *renderer) writeWrapped(ptrs int, t reflect.Type, implicit bool, text string) {
+	kind := TokenLiteral
+	if strings.HasPrefix(text, `"`) {
+		kind = TokenString
 	}
-	return fs
+	r.writeWrappedToken(ptrs, t, implicit, text, kind)
 }
 
-func (s *traverseState) render(buf *bytes.Buffer, ptrs int, v reflect.Value, implicit bool) {
-	if v.Kind() == reflect.Invalid {
-		buf.WriteString("nil")
+// writeWrappedToken is like writeWrapped, with text being a token of kind kind.
+func (r *renderer) writeWrappedToken(ptrs int, t reflect.Type, implicit bool, text string, kind TokenKind) {
+	buf := &r.buf
+	if implicit {
+		r.writeToken(kind, text)
 		return
 	}
-	vt := v.Type()
+	r.writeType(ptrs, t)
+	buf.WriteRune('(')
+	r.writeToken(kind, text)
+	buf.WriteRune(')')
+}
 
-	// If the type being rendered is a potentially recursive type (a type that
-	// can contain itself as a member), we need to avoid recursion.
-	//
-	// If we've already seen this type before, mark that this is the case and
-	// write a recursion placeholder instead of actually rendering it.
-	//
-	// If we haven't seen it before, fork our `seen` tracking so any higher-up
-	// renderers will also render it at least once, then mark that we've seen it
-	// to avoid recursing on lower layers.
-	pe := uintptr(0)
-	vk := vt.Kind()
-	switch vk {
-	case reflect.Ptr:
-		// Since structs and arrays aren't pointers, they can't directly be
-		// recursed, but they can contain pointers to themselves. Record their
-		// pointer to avoid this.
-		switch v.Elem().Kind() {
-		case reflect.Struct, reflect.Array:
-			pe = v.Pointer()
+// beginElem writes the separator that precedes the i'th element of a struct,
+// slice, array, or map.
+func (r *renderer) beginElem(i int) {
+	if r.opts.Indent == "" || r.inline {
+		if i > 0 {
+			r.buf.WriteString(r.itemSep())
 		}
+		return
+	}
 
-	case reflect.Slice, reflect.Map:
-		pe = v.Pointer()
+	if i > 0 {
+		r.buf.WriteString(strings.TrimRight(r.itemSep(), " "))
+		r.writePathComment()
 	}
-	if pe != 0 {
-		s = s.forkFor(pe)
-		if s == nil {
-			buf.WriteString("<REC(")
-			if !implicit {
-				writeType(buf, ptrs, vt)
-			}
-			buf.WriteString(")>")
-			return
-		}
+	r.newline(r.depth)
+}
+
+// endElems writes the separator that follows the last of n elements of a
+// struct, slice, array, or map.
+func (r *renderer) endElems(n int) {
+	if r.opts.Indent != "" && !r.inline && n > 0 {
+		r.buf.WriteString(strings.TrimRight(r.itemSep(), " "))
+		r.writePathComment()
+		r.newline(r.depth)
 	}
+}
 
-	isAnon := func(t reflect.Type) bool {
-		if t.Name() != "" {
-			if _, ok := builtinTypeSet[t.Name()]; !ok {
-				return false
-			}
-		}
-		return t.Kind() != reflect.Interface
+// notePath records the path of the element just rendered, to be written in a
+// comment at the end of its line (see PathComments).
+func (r *renderer) notePath() {
+	if r.opts.PathComments && r.opts.Indent != "" && !r.inline {
+		r.pathComment = r.path
 	}
+}
 
-	switch vk {
-	case reflect.Struct:
-		if !implicit {
-			writeType(buf, ptrs, vt)
-		}
-		buf.WriteRune('{')
-		if rendered, ok := renderTime(v); ok {
-			buf.WriteString(rendered)
-		} else {
-			structAnon := vt.Name() == ""
-			for i := 0; i < vt.NumField(); i++ {
-				if i > 0 {
-					buf.WriteString(", ")
-				}
-				anon := structAnon && isAnon(vt.Field(i).Type)
+// writePathComment writes the comment recorded by notePath, if any.
+func (r *renderer) writePathComment() {
+	if r.pathComment != "" {
+		r.buf.WriteByte(' ')
+		r.writeToken(TokenMarker, "// ."+r.pathComment)
+		r.pathComment = ""
+	}
+}
 
-				if !anon {
-					buf.WriteString(vt.Field(i).Name)
-					buf.WriteRune(':')
-				}
+// elidesElemType returns true if the type of the elements of slices or arrays
+// of type t is to be elided (see ElideElemTypes).
+func (r *renderer) elidesElemType(t reflect.Type) bool {
+	return r.opts.ElideElemTypes && t.Name() == "" && t.Elem().Kind() != reflect.Interface
+}
 
-				s.render(buf, 0, v.Field(i), anon)
-			}
-		}
-		buf.WriteRune('}')
+// shortenKey cuts the map key written from start down to MaxKeyLen bytes,
+// followed by "...", if it is longer.
+func (r *renderer) shortenKey(start int) {
+	max := r.opts.MaxKeyLen
+	if max <= 0 || r.buf.Len()-start <= max {
+		return
+	}
+	cut := start + max
+	for cut > start && !utf8.RuneStart(r.buf.Bytes()[cut]) {
+		cut--
+	}
+	if r.opts.Color {
+		cut = start + trimPartialEscape(r.buf.Bytes()[start:cut])
+	}
+	r.buf.Truncate(cut)
+	r.cutSpans(cut)
+	r.endColor()
+	r.writeToken(TokenTruncated, "...")
+}
 
-	case reflect.Slice:
-		if v.IsNil() {
-			if !implicit {
-				writeType(buf, ptrs, vt)
-				buf.WriteString("(nil)")
-			} else {
-				buf.WriteString("nil")
-			}
-			return
-		}
-		fallthrough
+// writeNil writes the nil token, in parentheses if wrapped is set.
+func (r *renderer) writeNil(wrapped bool) {
+	if wrapped {
+		r.buf.WriteRune('(')
+	}
+	r.writeToken(TokenLiteral, r.nilToken())
+	if wrapped {
+		r.buf.WriteRune(')')
+	}
+}
 
-	case reflect.Array:
-		if !implicit {
-			writeType(buf, ptrs, vt)
-		}
-		anon := vt.Name() == "" && isAnon(vt.Elem())
-		buf.WriteString("{")
-		for i := 0; i < v.Len(); i++ {
-			if i > 0 {
-				buf.WriteString(", ")
-			}
+// nilToken returns the token written for nil values.
+func (r *renderer) nilToken() string {
+	if r.opts.NilToken != "" {
+		return r.opts.NilToken
+	}
+	return "nil"
+}
 
-			s.render(buf, 0, v.Index(i), anon)
-		}
-		buf.WriteRune('}')
+// boolToken returns the token written for the boolean b.
+func (r *renderer) boolToken(b bool) string {
+	switch {
+	case b && r.opts.TrueToken != "":
+		return r.opts.TrueToken
+	case !b && r.opts.FalseToken != "":
+		return r.opts.FalseToken
+	case r.opts.BoolAsInt && b:
+		return "1"
+	case r.opts.BoolAsInt:
+		return "0"
+	}
+	return strconv.FormatBool(b)
+}
 
-	case reflect.Map:
-		if !implicit {
-			writeType(buf, ptrs, vt)
-		}
-		if v.IsNil() {
-			buf.WriteString("(nil)")
-		} else {
-			buf.WriteString("{")
+// writeCap writes the length and capacity of the non-nil slice v, if ShowCap
+// is set.
+func (r *renderer) writeCap(v reflect.Value) {
+	if r.opts.ShowCap {
+		r.writeToken(TokenMarker, fmt.Sprintf("(len=%d,cap=%d)", v.Len(), v.Cap()))
+	}
+}
 
-			mkeys := v.MapKeys()
-			tryAndSortMapKeys(vt, mkeys)
+// writeMore writes the marker standing for the last n of total elements, which
+// are left out.
+func (r *renderer) writeMore(n, total int) {
+	r.writeToken(TokenTruncated, fmt.Sprintf("...(+%d more of %d)", n, total))
+}
 
-			kt := vt.Key()
-			keyAnon := typeOfString.ConvertibleTo(kt) || typeOfInt.ConvertibleTo(kt) || typeOfUint.ConvertibleTo(kt) || typeOfFloat.ConvertibleTo(kt)
-			valAnon := vt.Name() == "" && isAnon(vt.Elem())
-			for i, mk := range mkeys {
-				if i > 0 {
-					buf.WriteString(", ")
-				}
+// writeIndex writes the index i of a slice or array element, if ShowIndices
+// is set.
+func (r *renderer) writeIndex(i int) {
+	if r.opts.ShowIndices {
+		r.writeToken(TokenKey, strconv.Itoa(i))
+		r.buf.WriteString(r.kvSep())
+	}
+}
 
-				s.render(buf, 0, mk, keyAnon)
-				buf.WriteString(":")
-				s.render(buf, 0, v.MapIndex(mk), valAnon)
-			}
-			buf.WriteRune('}')
-		}
+// itemSep returns the separator written between elements.
+func (r *renderer) itemSep() string {
+	if r.opts.ItemSep != "" {
+		return r.opts.ItemSep
+	}
+	return ", "
+}
 
-	case reflect.Ptr:
-		ptrs++
-		fallthrough
-	case reflect.Interface:
-		if v.IsNil() {
-			writeType(buf, ptrs, v.Type())
-			buf.WriteString("(nil)")
-		} else {
-			s.render(buf, ptrs, v.Elem(), false)
-		}
+// kvSep returns the separator written between map keys or struct field names
+// and their values.
+func (r *renderer) kvSep() string {
+	if r.opts.MapKVSep != "" {
+		return r.opts.MapKVSep
+	}
+	return ":"
+}
 
-	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
-		writeType(buf, ptrs, vt)
-		buf.WriteRune('(')
-		renderPointer(buf, v.Pointer())
-		buf.WriteRune(')')
+// writeAddress writes the pointer value p.
+func (r *renderer) writeAddress(p uintptr) {
+	if r.opts.formatPointer != nil {
+		r.opts.formatPointer(&r.buf, p)
+		return
+	}
+	fmt.Fprintf(&r.buf, "0x%016x", p)
+}
 
-	default:
-		tstr := vt.String()
-		implicit = implicit || (ptrs == 0 && builtinTypeMap[vk] == tstr)
-		if !implicit {
-			writeType(buf, ptrs, vt)
-			buf.WriteRune('(')
+// newline starts a new line indented to depth.
+func (r *renderer) newline(depth int) {
+	r.buf.WriteRune('\n')
+	for i := 0; i < depth; i++ {
+		r.buf.WriteString(r.opts.Indent)
+	}
+}
+
+// renderRuns renders the elements of the slice or array v, collapsing runs of
+// at least MinRunLength consecutive elements that render identically into a
+// single "elem xN" entry. It returns the number of entries written.
+func (r *renderer) renderRuns(s *traverseState, v reflect.Value, implicit bool) int {
+	buf := &r.buf
+	start := buf.Len()
+	elide := r.elidesElemType(v.Type())
+	elems := make([]string, 0, v.Len())
+	var elemSpans [][]span
+	for i := 0; i < v.Len(); i++ {
+		if r.exhausted() {
+			break
 		}
+		r.elideType = elide
+		parent := r.enterIndex(i)
+		r.render(s, 0, v.Index(i), implicit)
+		r.path = parent
+		elems = append(elems, string(buf.Bytes()[start:]))
+		elemSpans = append(elemSpans, r.takeSpans(start))
+		buf.Truncate(start)
+	}
 
-		switch vk {
-		case reflect.String:
-			fmt.Fprintf(buf, "%q", v.String())
-		case reflect.Bool:
-			fmt.Fprintf(buf, "%v", v.Bool())
+	written := 0
+	for i := 0; i < len(elems); {
+		j := i + 1
+		for j < len(elems) && elems[j] == elems[i] {
+			j++
+		}
 
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			fmt.Fprintf(buf, "%d", v.Int())
+		r.beginElem(written)
+		written++
+		r.writeIndex(i)
+		r.putSpans(elemSpans[i], buf.Len())
+		buf.WriteString(elems[i])
+		if n := j - i; n >= r.opts.MinRunLength {
+			buf.WriteByte(' ')
+			r.writeToken(TokenMarker, "x"+strconv.Itoa(n))
+			i = j
+		} else {
+			i++
+		}
+	}
+	return written
+}
 
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-			fmt.Fprintf(buf, "%d", v.Uint())
+// writeType writes the type t, preceded by ptrs pointer indirections, in the
+// type color.
+func (r *renderer) writeType(ptrs int, t reflect.Type) {
+	r.startColor(colorType)
+	start := r.buf.Len()
+	r.writeTypeName(ptrs, t)
+	r.emit(TokenType, start)
+	r.endColor()
+}
 
-		case reflect.Float32, reflect.Float64:
-			fmt.Fprintf(buf, "%g", v.Float())
+// typeText returns the name of the type t, as written by writeTypeName.
+func (r *renderer) typeText(t reflect.Type) string {
+	tr := renderer{opts: r.opts}
+	tr.writeTypeName(0, t)
+	return tr.buf.String()
+}
 
-		case reflect.Complex64, reflect.Complex128:
-			fmt.Fprintf(buf, "%g", v.Complex())
+// writeCommonElemType writes a comment naming the dynamic type of the elements
+// of the interface slice or array v, if they all share one, e.g.
+// ` /* all int */`.
+func (r *renderer) writeCommonElemType(v reflect.Value) {
+	if v.Type().Elem().Kind() != reflect.Interface || v.Len() == 0 {
+		return
+	}
+	var common reflect.Type
+	for i := 0; i < v.Len(); i++ {
+		e := v.Index(i)
+		if e.IsNil() {
+			return
 		}
+		if t := e.Elem().Type(); common == nil {
+			common = t
+		} else if t != common {
+			return
+		}
+	}
+	r.buf.WriteByte(' ')
+	r.writeToken(TokenMarker, "/* all "+r.typeText(common)+" */")
+}
 
-		if !implicit {
-			buf.WriteRune(')')
+// typeString returns the name of the type t, qualified with its package's
+// import path if FullPkgPath is set, or with its last element if
+// ShortPkgNames is set.
+func (r *renderer) typeString(t reflect.Type) string {
+	if t.Name() != "" && t.PkgPath() != "" {
+		switch {
+		case r.opts.FullPkgPath:
+			return t.PkgPath() + "." + t.Name()
+		case r.opts.ShortPkgNames:
+			return path.Base(t.PkgPath()) + "." + t.Name()
 		}
 	}
+	return t.String()
 }
 
-func writeType(buf *bytes.Buffer, ptrs int, t reflect.Type) {
+// writeTypeName writes the type t, preceded by ptrs pointer indirections.
+func (r *renderer) writeTypeName(ptrs int, t reflect.Type) {
+	buf := &r.buf
 	parens := ptrs > 0
 	switch t.Kind() {
-	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+	case reflect.Chan, reflect.Func:
 		parens = true
 	}
 
@@ -285,60 +1652,88 @@ func writeType(buf *bytes.Buffer, ptrs int, t reflect.Type) {
 	switch t.Kind() {
 	case reflect.Ptr:
 		if ptrs == 0 {
-			// This pointer was referenced from within writeType (e.g., as part of
-			// rendering a list), and so hasn't had its pointer asterisk accounted
-			// for.
+			// This pointer was referenced from within writeTypeName (e.g., as
+			// part of rendering a list), and so hasn't had its pointer asterisk
+			// accounted for.
 			buf.WriteRune('*')
 		}
-		writeType(buf, 0, t.Elem())
+		r.writeTypeName(0, t.Elem())
 
 	case reflect.Interface:
 		if n := t.Name(); n != "" {
-			buf.WriteString(t.String())
+			buf.WriteString(r.typeString(t))
 		} else {
 			buf.WriteString("any")
 		}
 
 	case reflect.Array:
-		buf.WriteRune('[')
-		buf.WriteString(strconv.FormatInt(int64(t.Len()), 10))
-		buf.WriteRune(']')
-		writeType(buf, 0, t.Elem())
+		if t == reflect.ArrayOf(t.Len(), t.Elem()) {
+			buf.WriteRune('[')
+			buf.WriteString(strconv.FormatInt(int64(t.Len()), 10))
+			buf.WriteRune(']')
+			r.writeTypeName(0, t.Elem())
+		} else {
+			// Custom array type, use type name, as for slices and maps.
+			buf.WriteString(r.typeString(t))
+		}
 
 	case reflect.Slice:
 		if t == reflect.SliceOf(t.Elem()) {
 			buf.WriteString("[]")
-			writeType(buf, 0, t.Elem())
+			r.writeTypeName(0, t.Elem())
 		} else {
 			// Custom slice type, use type name.
-			buf.WriteString(t.String())
+			buf.WriteString(r.typeString(t))
 		}
 
 	case reflect.Map:
 		if t == reflect.MapOf(t.Key(), t.Elem()) {
 			buf.WriteString("map[")
-			writeType(buf, 0, t.Key())
+			r.writeTypeName(0, t.Key())
 			buf.WriteRune(']')
-			writeType(buf, 0, t.Elem())
+			r.writeTypeName(0, t.Elem())
 		} else {
 			// Custom map type, use type name.
-			buf.WriteString(t.String())
+			buf.WriteString(r.typeString(t))
+		}
+
+	case reflect.Struct:
+		if r.opts.ShortAnonStructs && t.Name() == "" {
+			buf.WriteString("struct{...}")
+		} else {
+			buf.WriteString(r.typeString(t))
 		}
 
 	default:
-		buf.WriteString(t.String())
+		if r.opts.ByteAlias && t == typeOfByte {
+			buf.WriteString("byte")
+		} else {
+			buf.WriteString(r.typeString(t))
+		}
 	}
 
 	if parens {
+		if ptrs > 0 && r.addr != 0 {
+			buf.WriteString(" @")
+			if id, ok := r.keyIDs[r.addr]; ok {
+				buf.WriteString("#" + strconv.Itoa(id))
+			} else {
+				r.writeAddress(r.addr)
+			}
+			r.addr = 0
+		}
 		buf.WriteRune(')')
 	}
 }
 
 type cmpFn func(a, b reflect.Value) int
 
+// sortableValueSlice sorts elements using cmp. If values is not nil, it is
+// kept parallel to elements.
 type sortableValueSlice struct {
 	cmp      cmpFn
 	elements []reflect.Value
+	values   []reflect.Value
 }
 
 func (s sortableValueSlice) Len() int {
@@ -351,10 +1746,14 @@ func (s sortableValueSlice) Less(i, j int) bool {
 
 func (s sortableValueSlice) Swap(i, j int) {
 	s.elements[i], s.elements[j] = s.elements[j], s.elements[i]
+	if s.values != nil {
+		s.values[i], s.values[j] = s.values[j], s.values[i]
+	}
 }
 
 // cmpForType returns a cmpFn which sorts the data for some type t in the same
-// order that a go-native map key is compared for equality.
+// order that a go-native map key is compared for equality, or nil if values of
+// type t can't be map keys.
 func cmpForType(t reflect.Type) cmpFn {
 	switch t.Kind() {
 	case reflect.String:
@@ -391,7 +1790,7 @@ func cmpForType(t reflect.Type) cmpFn {
 		}
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
-		reflect.Uint64, reflect.Uintptr, reflect.UnsafePointer:
+		reflect.Uint64, reflect.Uintptr:
 		return func(av, bv reflect.Value) int {
 			a, b := av.Uint(), bv.Uint()
 			if a < b {
@@ -404,48 +1803,22 @@ func cmpForType(t reflect.Type) cmpFn {
 
 	case reflect.Float32, reflect.Float64:
 		return func(av, bv reflect.Value) int {
-			a, b := av.Float(), bv.Float()
-			if a < b {
-				return -1
-			} else if a > b {
-				return 1
-			}
-			return 0
+			return cmpFloat(av.Float(), bv.Float())
 		}
 
 	case reflect.Interface:
-		return func(av, bv reflect.Value) int {
-			a, b := av.InterfaceData(), bv.InterfaceData()
-			if a[0] < b[0] {
-				return -1
-			} else if a[0] > b[0] {
-				return 1
-			}
-			if a[1] < b[1] {
-				return -1
-			} else if a[1] > b[1] {
-				return 1
-			}
-			return 0
-		}
+		return cmpInterface
 
 	case reflect.Complex64, reflect.Complex128:
 		return func(av, bv reflect.Value) int {
 			a, b := av.Complex(), bv.Complex()
-			if real(a) < real(b) {
-				return -1
-			} else if real(a) > real(b) {
-				return 1
-			}
-			if imag(a) < imag(b) {
-				return -1
-			} else if imag(a) > imag(b) {
-				return 1
+			if rslt := cmpFloat(real(a), real(b)); rslt != 0 {
+				return rslt
 			}
-			return 0
+			return cmpFloat(imag(a), imag(b))
 		}
 
-	case reflect.Ptr, reflect.Chan:
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
 		return func(av, bv reflect.Value) int {
 			a, b := av.Pointer(), bv.Pointer()
 			if a < b {
@@ -469,13 +1842,241 @@ func cmpForType(t reflect.Type) cmpFn {
 			}
 			return 0
 		}
+
+	case reflect.Array:
+		cmp := cmpForType(t.Elem())
+		return func(a, b reflect.Value) int {
+			for i := 0; i < a.Len(); i++ {
+				if rslt := cmp(a.Index(i), b.Index(i)); rslt != 0 {
+					return rslt
+				}
+			}
+			return 0
+		}
 	}
 
 	return nil
 }
 
-func tryAndSortMapKeys(mt reflect.Type, k []reflect.Value) {
-	if cmp := cmpForType(mt.Key()); cmp != nil {
-		sort.Sort(sortableValueSlice{cmp, k})
+// cmpRendered compares two values by their rendered form. It orders map keys
+// that cmpForType considers equal (see mapEntrySlice).
+func cmpRendered(av, bv reflect.Value) int {
+	return strings.Compare(renderedString(av, &RenderOptions{}), renderedString(bv, &RenderOptions{}))
+}
+
+// renderedString renders v with opts. Unlike RenderWith, it works on values
+// obtained through unexported fields.
+func renderedString(v reflect.Value, opts *RenderOptions) string {
+	r := renderer{opts: opts}
+	r.render(nil, 0, v, false)
+	return r.buf.String()
+}
+
+// cmpFloat imposes a total order on floats: -Inf first, then finite values
+// ascending, then +Inf, and finally NaN.
+func cmpFloat(a, b float64) int {
+	switch an, bn := math.IsNaN(a), math.IsNaN(b); {
+	case an && bn:
+		return 0
+	case an:
+		return 1
+	case bn:
+		return -1
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// cmpInterface compares two interface values. Nil values sort first, then
+// values are grouped by their dynamic type, and values sharing a dynamic type
+// are compared using that type's cmpFn.
+func cmpInterface(av, bv reflect.Value) int {
+	if av.IsNil() || bv.IsNil() {
+		switch {
+		case av.IsNil() && bv.IsNil():
+			return 0
+		case av.IsNil():
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	ae, be := av.Elem(), bv.Elem()
+	if at, bt := ae.Type(), be.Type(); at != bt {
+		if a, b := at.String(), bt.String(); a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+		if a, b := at.PkgPath(), bt.PkgPath(); a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+		return 0
+	}
+
+	if cmp := cmpForType(ae.Type()); cmp != nil {
+		return cmp(ae, be)
+	}
+	return 0
+}
+
+// SortedMapKeys returns the keys of the map m in the same order that Render
+// uses when rendering it. Keys that are equal as far as their type's ordering
+// is concerned are ordered by their rendered form.
+//
+// Keys are ordered by their underlying values, even if they implement
+// fmt.Stringer or error, so the order doesn't depend on the Stringers and
+// Errors options.
+//
+// If m is the zero Value, SortedMapKeys returns nil.
+func SortedMapKeys(m reflect.Value) []reflect.Value {
+	keys, _ := sortedMapEntries(m)
+	return keys
+}
+
+// mapEntries returns the keys of the map m and their associated values, in
+// the order set by MapOrder and MapKeyLess.
+func (r *renderer) mapEntries(m reflect.Value) (keys, values []reflect.Value) {
+	if r.opts.MapOrder != Unsorted {
+		keys, values = sortedMapEntries(m)
+		if r.opts.NumericStringKeys && m.Type().Key().Kind() == reflect.String {
+			sortNumericKeys(keys, values)
+		}
+		if less := r.opts.MapKeyLess; less != nil {
+			// Sort stably, so that keys which less considers equal keep their
+			// default order.
+			sort.Stable(sortableValueSlice{func(a, b reflect.Value) int {
+				if less(a, b) {
+					return -1
+				}
+				return 0
+			}, keys, values})
+		}
+		return keys, values
+	}
+	for it := m.MapRange(); it.Next(); {
+		keys = append(keys, it.Key())
+		values = append(values, it.Value())
+	}
+	return keys, values
+}
+
+// sortNumericKeys sorts the string keys and their values by the numbers the
+// keys hold, if they all hold decimal numbers such as "10" or "-1.5".
+func sortNumericKeys(keys, values []reflect.Value) {
+	nums := make(map[string]float64, len(keys))
+	for _, k := range keys {
+		n, ok := numericString(k.String())
+		if !ok {
+			return
+		}
+		nums[k.String()] = n
+	}
+	sort.Stable(sortableValueSlice{func(a, b reflect.Value) int {
+		return cmpFloat(nums[a.String()], nums[b.String()])
+	}, keys, values})
+}
+
+// numericString returns the number held by s, if it is a decimal number.
+func numericString(s string) (float64, bool) {
+	if strings.Trim(s, "+-.0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
+}
+
+// pointerIDs numbers the non-nil pointers in ptrs from 1, in address order.
+func pointerIDs(ptrs []reflect.Value) map[uintptr]int {
+	var addrs []uintptr
+	for _, p := range ptrs {
+		if !p.IsNil() {
+			addrs = append(addrs, p.Pointer())
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i] < addrs[j] })
+	ids := make(map[uintptr]int, len(addrs))
+	for i, a := range addrs {
+		ids[a] = i + 1
+	}
+	return ids
+}
+
+// compressMapPath follows the chain of maps with a single entry and string
+// keys that starts at val, the value of an entry of the map m (see
+// CompressMapPaths). It returns the keys along the chain, the value at its
+// end, and the type of the map holding that value. The chain stops short of
+// maps seen before, so that cycles are left to be detected when rendering.
+func compressMapPath(m, val reflect.Value) (keys []reflect.Value, last reflect.Value, holder reflect.Type) {
+	seen := map[uintptr]bool{m.Pointer(): true}
+	last, holder = val, m.Type()
+	for {
+		inner := last
+		if inner.Kind() == reflect.Interface && !inner.IsNil() {
+			inner = inner.Elem()
+		}
+		if inner.Kind() != reflect.Map || inner.Type().Key().Kind() != reflect.String ||
+			inner.Len() != 1 || seen[inner.Pointer()] {
+			return keys, last, holder
+		}
+		seen[inner.Pointer()] = true
+		it := inner.MapRange()
+		it.Next()
+		keys = append(keys, it.Key())
+		last, holder = it.Value(), inner.Type()
+	}
+}
+
+// sortedMapEntries returns the keys of the map m and their associated values,
+// ordered as in SortedMapKeys.
+//
+// Values are gathered by iterating the map rather than looking them up by key,
+// as keys such as NaN cannot be looked up.
+func sortedMapEntries(m reflect.Value) (keys, values []reflect.Value) {
+	if !m.IsValid() {
+		return nil, nil
+	}
+	keys = make([]reflect.Value, 0, m.Len())
+	values = make([]reflect.Value, 0, m.Len())
+	for it := m.MapRange(); it.Next(); {
+		keys = append(keys, it.Key())
+		values = append(values, it.Value())
+	}
+	sort.Sort(mapEntrySlice{cmpForType(m.Type().Key()), keys, values})
+	return keys, values
+}
+
+// mapEntrySlice sorts the keys of a map using cmp, keeping values parallel to
+// them. Keys that cmp considers equal, such as values of distinct types with
+// the same name held in interfaces, are ordered by their rendered form, and
+// then by that of their values, so that their order depends on neither memory
+// addresses nor map iteration order.
+type mapEntrySlice struct {
+	cmp          cmpFn
+	keys, values []reflect.Value
+}
+
+func (s mapEntrySlice) Len() int {
+	return len(s.keys)
+}
+
+func (s mapEntrySlice) Less(i, j int) bool {
+	if rslt := s.cmp(s.keys[i], s.keys[j]); rslt != 0 {
+		return rslt < 0
+	}
+	if rslt := cmpRendered(s.keys[i], s.keys[j]); rslt != 0 {
+		return rslt < 0
 	}
+	return cmpRendered(s.values[i], s.values[j]) < 0
+}
+
+func (s mapEntrySlice) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
 }
@@ -0,0 +1,51 @@
+package render
+
+import "reflect"
+
+// atomicValue returns the value loaded from v, if v is one of the types of
+// sync/atomic, such as atomic.Int64 or atomic.Value, which are detected by
+// their Load method rather than by name, so that types added in later Go
+// versions are covered as well.
+func atomicValue(v reflect.Value) (reflect.Value, bool) {
+	t := v.Type()
+	if t.PkgPath() != "sync/atomic" || t.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	v, ok := exportedValue(v)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	if !v.CanAddr() {
+		// Load needs a pointer receiver.
+		cv := reflect.New(t).Elem()
+		cv.Set(v)
+		v = cv
+	}
+	load := v.Addr().MethodByName("Load")
+	if !load.IsValid() || load.Type().NumIn() != 0 || load.Type().NumOut() != 1 {
+		return reflect.Value{}, false
+	}
+	return load.Call(nil)[0], true
+}
+
+// renderAtomic renders v as the value loaded from it, if v is a sync/atomic
+// type, e.g. `atomic.Int64(42)`. It returns false otherwise.
+func (r *renderer) renderAtomic(s *traverseState, ptrs int, v reflect.Value, implicit bool) bool {
+	loaded, ok := atomicValue(v)
+	if !ok {
+		return false
+	}
+	if !implicit {
+		r.writeType(ptrs, v.Type())
+		r.openBracket('(', ')')
+	}
+	if loaded.Kind() == reflect.Interface {
+		// atomic.Value holds an any, whose dynamic value is rendered.
+		loaded = loaded.Elem()
+	}
+	r.render(s, 0, loaded, false)
+	if !implicit {
+		r.closeBracket(')')
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+//go:build go1.19
+
+package render
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestRenderAtomics(t *testing.T) {
+	type item struct{ N int }
+	type holder struct {
+		I atomic.Int64
+		v atomic.Value
+	}
+
+	var i atomic.Int64
+	i.Store(42)
+	var b atomic.Bool
+	b.Store(true)
+	var u atomic.Uint64
+	u.Store(7)
+	var p atomic.Pointer[int]
+	n := 3
+	p.Store(&n)
+	var empty, num, st atomic.Value
+	num.Store(5)
+	st.Store(item{N: 2})
+
+	assertRendersLike(t, "Int64", &i, `(*atomic.Int64)(42)`)
+	assertRendersLike(t, "Bool", &b, `(*atomic.Bool)(true)`)
+	assertRendersLike(t, "Uint64", &u, `(*atomic.Uint64)(7)`)
+	assertRendersLike(t, "Pointer", &p, `(*atomic.Pointer[int])((*int)(3))`)
+	assertRendersLike(t, "Nil pointer", &atomic.Pointer[int]{}, `(*atomic.Pointer[int])((*int)(nil))`)
+	assertRendersLike(t, "Empty value", &empty, `(*atomic.Value)(nil)`)
+	assertRendersLike(t, "Value", &num, `(*atomic.Value)(5)`)
+	assertRendersLike(t, "Struct value", &st, `(*atomic.Value)(render.item{N:2})`)
+	assertRendersLike(t, "Fields", &holder{}, `(*render.holder){I:atomic.Int64(0), v:atomic.Value(nil)}`)
+	assertRendersLike(t, "Map values", map[string]atomic.Int32{"a": {}}, `map[string]atomic.Int32{"a":atomic.Int32(0)}`)
+}
@@ -0,0 +1,70 @@
+package render
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var typeOfBytes = reflect.TypeOf([]byte(nil))
+
+// renderBytes renders a non-empty byte slice as a quoted string if it holds
+// printable UTF-8 text, or as a hex dump otherwise.
+func (r *renderer) renderBytes(ptrs int, v reflect.Value, implicit bool) {
+	buf := &r.buf
+	if !implicit {
+		start := buf.Len()
+		if v.Type() == typeOfBytes {
+			if ptrs > 0 {
+				buf.WriteRune('(')
+				buf.WriteString(strings.Repeat("*", ptrs))
+				buf.WriteString("[]byte)")
+			} else {
+				buf.WriteString("[]byte")
+			}
+		} else {
+			r.writeType(ptrs, v.Type())
+		}
+		r.emit(TokenType, start)
+		buf.WriteRune('(')
+	}
+
+	if b := v.Bytes(); isPrintableText(b) {
+		r.writeToken(TokenString, strconv.Quote(string(b)))
+	} else {
+		r.writeToken(TokenNumber, "0x"+hex.EncodeToString(b))
+	}
+
+	if !implicit {
+		buf.WriteRune(')')
+	}
+}
+
+// uuidText returns the canonical UUID form of v if it is a [16]byte array to
+// be rendered as a UUID (see UUIDs).
+func (r *renderer) uuidText(v reflect.Value) (string, bool) {
+	if !r.opts.UUIDs || v.Kind() != reflect.Array || v.Len() != 16 || v.Type().Elem().Kind() != reflect.Uint8 {
+		return "", false
+	}
+	var b [16]byte
+	for i := range b {
+		b[i] = byte(v.Index(i).Uint())
+	}
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], true
+}
+
+func isPrintableText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, c := range string(b) {
+		if !unicode.IsPrint(c) && !unicode.IsSpace(c) {
+			return false
+		}
+	}
+	return true
+}
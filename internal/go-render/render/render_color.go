@@ -0,0 +1,48 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// ANSI escape codes used by the Color option.
+const (
+	colorType      = "\x1b[36m"
+	colorString    = "\x1b[32m"
+	colorRecursion = "\x1b[31m"
+	colorReset     = "\x1b[0m"
+)
+
+// startColor starts writing in color c, if the Color option is set.
+func (r *renderer) startColor(c string) {
+	if r.opts.Color {
+		r.buf.WriteString(c)
+	}
+}
+
+// endColor resets the color, if the Color option is set.
+func (r *renderer) endColor() {
+	if r.opts.Color {
+		r.buf.WriteString(colorReset)
+	}
+}
+
+// trimPartialEscape returns the length of b without the incomplete escape
+// sequence it may end with.
+func trimPartialEscape(b []byte) int {
+	if i := bytes.LastIndexByte(b, '\x1b'); i >= 0 && bytes.IndexByte(b[i:], 'm') < 0 {
+		return i
+	}
+	return len(b)
+}
+
+// isTerminal returns true if w writes to a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,19 @@
+package render
+
+import (
+	"context"
+	"reflect"
+)
+
+// ctxCheckInterval is the number of values rendered between checks of the
+// context passed to RenderCtx.
+const ctxCheckInterval = 64
+
+// RenderCtx is like Render, but stops early if ctx is done. The context is
+// checked periodically as values are rendered; if it is done, RenderCtx
+// returns whatever was rendered so far along with the context's error.
+func RenderCtx(ctx context.Context, v any) (string, error) {
+	r := renderer{opts: &RenderOptions{}, ctx: ctx}
+	r.run(reflect.ValueOf(v))
+	return r.buf.String(), r.err
+}
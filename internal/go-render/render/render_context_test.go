@@ -0,0 +1,46 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRenderCtx(t *testing.T) {
+	type node struct {
+		Children []*node
+	}
+
+	// A wide tree with 1+100+100*100 nodes.
+	root := &node{}
+	for i := 0; i < 100; i++ {
+		child := &node{}
+		for j := 0; j < 100; j++ {
+			child.Children = append(child.Children, &node{})
+		}
+		root.Children = append(root.Children, child)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	act, err := RenderCtx(ctx, root)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a context.Canceled error, got: %v", err)
+	}
+	if full := Render(root); len(act) >= len(full) || full[:len(act)] != act {
+		t.Errorf("expected a partial rendering, got: %s", act)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	act, err = RenderCtx(ctx, root)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if exp := Render(root); act != exp {
+		t.Errorf("expected the full rendering:\nExpected: %s\nActual  : %s", exp, act)
+	}
+}
@@ -0,0 +1,174 @@
+package render
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RenderDiff describes the differences between the renderings of a and b, one
+// per line, or returns "" if they render identically.
+//
+// Struct fields, slice and array elements, and map entries are compared
+// individually, and each difference is reported with its path. Lines starting
+// with "- path: " and "+ path: " show values only present in a and in b
+// respectively. Changed values are shown as "~ path: x -> y", or as
+// "~ path: <set to nil>" if they changed to nil.
+//
+// A map entry holding nil is thus distinguished from a missing one.
+func RenderDiff(a, b any) string {
+	return renderDiff(a, b, &RenderOptions{})
+}
+
+// renderDiff is like RenderDiff, rendering values with opts.
+func renderDiff(a, b any, opts *RenderOptions) string {
+	d := differ{opts: opts, seen: map[[2]uintptr]bool{}}
+	d.diff("", reflect.ValueOf(a), reflect.ValueOf(b))
+	return strings.Join(d.lines, "\n")
+}
+
+// differ holds the state of a RenderDiff.
+type differ struct {
+	opts  *RenderOptions
+	lines []string
+
+	// seen holds the pairs of pointers, slices, and maps that have been
+	// compared, so that cyclic values are only compared once.
+	seen map[[2]uintptr]bool
+}
+
+// add reports a difference at path, described by text: '-' for values only
+// present in a, '+' for values only present in b, and '~' for changed values.
+func (d *differ) add(op byte, path, text string) {
+	if path != "" {
+		text = path + ": " + text
+	}
+	d.lines = append(d.lines, string(op)+" "+text)
+}
+
+// diff reports the differences between the values a and b found at path.
+// Values of the same type are compared structurally, so that only the values
+// that differ, or that have no structure to compare, are rendered.
+func (d *differ) diff(path string, a, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() || a.Type() != b.Type() {
+		d.compare(path, a, b)
+		return
+	}
+	if pair := [2]uintptr{recursionPointer(a), recursionPointer(b)}; pair[0] != 0 && pair[1] != 0 {
+		if d.seen[pair] {
+			return
+		}
+		d.seen[pair] = true
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			break
+		}
+		d.diff(path, a.Elem(), b.Elem())
+		return
+
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			break
+		}
+		d.diff(path, a.Elem(), b.Elem())
+		return
+
+	case reflect.Struct:
+		if a.Type() == timeType {
+			break
+		}
+		for i := 0; i < a.NumField(); i++ {
+			d.diff(joinPath(path, a.Type().Field(i).Name), a.Field(i), b.Field(i))
+		}
+		return
+
+	case reflect.Slice, reflect.Array:
+		if a.Kind() == reflect.Slice && (a.IsNil() || b.IsNil()) {
+			break
+		}
+		for i := 0; i < a.Len() || i < b.Len(); i++ {
+			elem := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= b.Len():
+				d.add('-', elem, renderedString(a.Index(i), d.opts))
+			case i >= a.Len():
+				d.add('+', elem, renderedString(b.Index(i), d.opts))
+			default:
+				d.diff(elem, a.Index(i), b.Index(i))
+			}
+		}
+		return
+
+	case reflect.Map:
+		if a.IsNil() || b.IsNil() {
+			break
+		}
+		d.diffMaps(path, a, b)
+		return
+	}
+	d.compare(path, a, b)
+}
+
+// compare reports a change at path if the values a and b render differently.
+func (d *differ) compare(path string, a, b reflect.Value) {
+	if at, bt := renderedString(a, d.opts), renderedString(b, d.opts); at != bt {
+		d.changed(path, at, bt, b)
+	}
+}
+
+// diffMaps compares the entries of the maps a and b, in key order.
+func (d *differ) diffMaps(path string, a, b reflect.Value) {
+	keys, _ := sortedMapEntries(a)
+	for _, k := range SortedMapKeys(b) {
+		if !a.MapIndex(k).IsValid() {
+			keys = append(keys, k)
+		}
+	}
+	cmp := cmpForType(a.Type().Key())
+	sort.SliceStable(keys, func(i, j int) bool {
+		return cmp(keys[i], keys[j]) < 0
+	})
+
+	for _, k := range keys {
+		entry := joinPath(path, renderedKey(k, d.opts))
+
+		av, bv := a.MapIndex(k), b.MapIndex(k)
+		switch {
+		case !bv.IsValid():
+			d.add('-', entry, renderedString(av, d.opts))
+		case !av.IsValid():
+			d.add('+', entry, renderedString(bv, d.opts))
+		default:
+			d.diff(entry, av, bv)
+		}
+	}
+}
+
+// changed reports that the value at path changed from the rendered text at to
+// the value b, rendered as bt.
+func (d *differ) changed(path, at, bt string, b reflect.Value) {
+	if !b.IsValid() || isNilValue(b) {
+		d.add('~', path, "<set to nil>")
+		return
+	}
+	d.add('~', path, at+" -> "+bt)
+}
+
+// renderedKey returns the map key k as rendered in paths, with opts.
+func renderedKey(k reflect.Value, opts *RenderOptions) string {
+	kr := renderer{opts: opts}
+	kr.render(nil, 0, k, true)
+	return kr.buf.String()
+}
+
+// joinPath appends the field or map key name to path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,81 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func assertDiffsLike(t *testing.T, name string, a, b any, exp string) {
+	t.Helper()
+	if act := renderDiff(a, b, &RenderOptions{formatPointer: ptrPlaceholder}); act != exp {
+		t.Errorf("[%s] did not match expectations:\nExpected:\n%s\nActual:\n%s", name, exp, act)
+	}
+}
+
+func TestRenderDiff(t *testing.T) {
+	type inner struct {
+		A int
+		B []string
+	}
+	type outer struct {
+		Name  string
+		Inner *inner
+		Tags  map[string]int
+	}
+
+	assertDiffsLike(t, "Equal", outer{Name: "x"}, outer{Name: "x"}, "")
+	assertDiffsLike(t, "Scalars", 1, 2, "~ 1 -> 2")
+	assertDiffsLike(t, "Different types", 1, "1", `~ 1 -> "1"`)
+	assertDiffsLike(t, "Channels", make(chan int), []int{}, "~ (chan int)(PTR) -> []int{}")
+	assertDiffsLike(t, "Fields",
+		outer{"x", &inner{1, []string{"a", "b"}}, map[string]int{"k": 1}},
+		outer{"y", &inner{1, []string{"a", "c", "d"}}, map[string]int{"k": 2}},
+		`~ Name: "x" -> "y"`+"\n"+
+			`~ Inner.B[1]: "b" -> "c"`+"\n"+
+			`+ Inner.B[2]: "d"`+"\n"+
+			`~ Tags."k": 1 -> 2`)
+	assertDiffsLike(t, "Removed element", []int{1, 2}, []int{1}, `- [1]: 2`)
+	assertDiffsLike(t, "Set to nil", outer{Inner: &inner{}}, outer{}, `~ Inner: <set to nil>`)
+	assertDiffsLike(t, "Set from nil", outer{}, outer{Inner: &inner{}},
+		`~ Inner: (*render.inner)(nil) -> (*render.inner){A:0, B:[]string(nil)}`)
+
+	// Cyclic values are compared once.
+	type node struct {
+		V    int
+		Next *node
+	}
+	a, b := &node{V: 1}, &node{V: 2}
+	a.Next, b.Next = a, b
+	assertDiffsLike(t, "Cycle", a, b, `~ V: 1 -> 2`)
+
+	ma, mb := map[string]any{"x": 1}, map[string]any{"x": 2}
+	ma["self"], mb["self"] = ma, mb
+	assertDiffsLike(t, "Map cycle", ma, mb, `~ "x": 1 -> 2`)
+
+	sa, sb := []any{1, nil}, []any{2, nil}
+	sa[1], sb[1] = sa, sb
+	assertDiffsLike(t, "Slice cycle", sa, sb, `~ [0]: 1 -> 2`)
+
+	// Deep values are compared structurally, down to the differing leaf.
+	long := func(last int) *node {
+		head := &node{V: last}
+		for i := 0; i < 500; i++ {
+			head = &node{V: i, Next: head}
+		}
+		return head
+	}
+	assertDiffsLike(t, "Deep", long(1), long(2), "~ "+strings.Repeat("Next.", 500)+"V: 1 -> 2")
+}
+
+func TestRenderDiffNilMapValues(t *testing.T) {
+	withNil := map[string]any{"k": nil, "other": 1}
+	without := map[string]any{"other": 1}
+	withValue := map[string]any{"k": "value", "other": 1}
+
+	assertDiffsLike(t, "Missing key", withValue, without, `- "k": "value"`)
+	assertDiffsLike(t, "Added key", without, withValue, `+ "k": "value"`)
+	assertDiffsLike(t, "Set to nil", withValue, withNil, `~ "k": <set to nil>`)
+	assertDiffsLike(t, "Nil to missing", withNil, without, `- "k": any(nil)`)
+	assertDiffsLike(t, "Missing to nil", without, withNil, `+ "k": any(nil)`)
+	assertDiffsLike(t, "Both nil", withNil, map[string]any{"other": 1, "k": nil}, "")
+}
@@ -0,0 +1,106 @@
+package render
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// RenderFields flattens v into a map of scalar values, for use with structured
+// loggers. Struct fields are keyed by their dotted path, e.g. "User.Addr.City",
+// slice and array elements by their index, e.g. "Tags.0", and map entries by
+// their key, rendered as in RenderJSON.
+//
+// Pointers and interfaces are followed, and values that would recurse are
+// stored as "<REC>". Nil values are stored as nil, and values without a scalar
+// equivalent, such as channels and funcs, as their rendered string. If v
+// itself is a scalar, it is stored under the empty key.
+func RenderFields(v any) map[string]any {
+	return renderFields(v, &RenderOptions{})
+}
+
+// renderFields is like RenderFields, rendering non-scalar values and map keys
+// with opts.
+func renderFields(v any, opts *RenderOptions) map[string]any {
+	fields := map[string]any{}
+	flattenFields(fields, nil, "", addressable(reflect.ValueOf(v)), opts)
+	return fields
+}
+
+func flattenFields(fields map[string]any, s *traverseState, key string, v reflect.Value, opts *RenderOptions) {
+	if !v.IsValid() {
+		fields[key] = nil
+		return
+	}
+	if pe := recursionPointer(v); pe != 0 {
+		if s = s.forkFor(pe); s == nil {
+			fields[key] = "<REC>"
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			fields[key] = nil
+			return
+		}
+		flattenFields(fields, s, key, v.Elem(), opts)
+
+	case reflect.Struct:
+		if v.Type() == timeType {
+			fields[key] = scalarValue(v, opts)
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			flattenFields(fields, s, joinPath(key, v.Type().Field(i).Name), v.Field(i), opts)
+		}
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			fields[key] = nil
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			flattenFields(fields, s, joinPath(key, strconv.Itoa(i)), v.Index(i), opts)
+		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			fields[key] = nil
+			return
+		}
+		keys, values := sortedMapEntries(v)
+		for i, k := range keys {
+			flattenFields(fields, s, joinPath(key, jsonKey(k, opts)), values[i], opts)
+		}
+
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		fields[key] = renderedString(v, opts)
+
+	default:
+		fields[key] = scalarValue(v, opts)
+	}
+}
+
+// scalarValue returns the value held by the scalar v, even if it was obtained
+// through unexported fields. Other values are rendered with opts.
+func scalarValue(v reflect.Value, opts *RenderOptions) any {
+	if ev, ok := exportedValue(v); ok {
+		return ev.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return v.Complex()
+	case reflect.String:
+		return v.String()
+	}
+	return renderedString(v, opts)
+}
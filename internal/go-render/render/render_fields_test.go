@@ -0,0 +1,75 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+)
+
+func assertFieldsLike(t *testing.T, name string, v any, exp map[string]any) {
+	t.Helper()
+	if act := renderFields(v, &RenderOptions{formatPointer: ptrPlaceholder}); !reflect.DeepEqual(act, exp) {
+		t.Errorf("[%s] did not match expectations:\nExpected: %s\nActual  : %s", name,
+			RenderWith(exp, RenderOptions{ShowDynamicTypes: true}), RenderWith(act, RenderOptions{ShowDynamicTypes: true}))
+	}
+}
+
+func TestRenderFields(t *testing.T) {
+	type address struct {
+		City string
+		zip  int
+	}
+	type user struct {
+		Name  string
+		Addr  *address
+		Admin bool
+	}
+	type event struct {
+		User user
+		ID   uint8
+	}
+
+	assertFieldsLike(t, "Two levels", event{user{"ann", &address{"Oslo", 150}, true}, 7}, map[string]any{
+		"User.Name":      "ann",
+		"User.Addr.City": "Oslo",
+		"User.Addr.zip":  150,
+		"User.Admin":     true,
+		"ID":             uint8(7),
+	})
+	assertFieldsLike(t, "Nil pointer", user{Name: "bob"}, map[string]any{
+		"Name":  "bob",
+		"Addr":  nil,
+		"Admin": false,
+	})
+	assertFieldsLike(t, "Scalar", 3.5, map[string]any{"": 3.5})
+	assertFieldsLike(t, "Channel", make(chan int), map[string]any{"": "(chan int)(PTR)"})
+
+	var x any
+	x = &x
+	assertFieldsLike(t, "Pointer to itself", x, map[string]any{"": "<REC>"})
+}
+
+func TestRenderFieldsCollections(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	type tagged struct {
+		Tags   []string
+		Counts map[int]bool
+		Any    any
+	}
+
+	assertFieldsLike(t, "Slice", tagged{Tags: []string{"a", "b"}, Counts: map[int]bool{2: true}, Any: []int{5}}, map[string]any{
+		"Tags.0":   "a",
+		"Tags.1":   "b",
+		"Counts.2": true,
+		"Any.0":    5,
+	})
+
+	n := &node{Name: "a"}
+	n.Next = n
+	assertFieldsLike(t, "Cycle", n, map[string]any{
+		"Name": "a",
+		"Next": "<REC>",
+	})
+}
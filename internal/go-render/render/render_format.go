@@ -0,0 +1,63 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Value wraps a value so that it is rendered when formatted by the fmt
+// package with the %v or %s verbs.
+//
+// By default the value is rendered without type prefixes (see
+// RenderOptions.OmitTypePrefix). The '#' flag includes them, and the '+' flag
+// renders the value indented across multiple lines. A width pads the output
+// with spaces, on the left unless the '-' flag is given, and a precision sets
+// the number of digits of floats (see RenderOptions.FloatPrecision), e.g.
+// "%.3v".
+type Value struct {
+	V any
+}
+
+// V wraps v in a Value.
+func V(v any) Value {
+	return Value{v}
+}
+
+// Format implements fmt.Formatter.
+func (v Value) Format(f fmt.State, verb rune) {
+	opts := RenderOptions{OmitTypePrefix: !f.Flag('#')}
+	if f.Flag('+') {
+		opts.Indent = "\t"
+	}
+	if p, ok := f.Precision(); ok {
+		// As with %g, a precision of zero is taken as one.
+		opts.FloatPrecision = p
+		if p == 0 {
+			opts.FloatPrecision = 1
+		}
+	}
+
+	switch verb {
+	case 'v', 's':
+	default:
+		fmt.Fprintf(f, "%%!%c(render.Value=%s)", verb, RenderWith(v.V, opts))
+		return
+	}
+
+	out := RenderWith(v.V, opts)
+	pad := ""
+	if w, ok := f.Width(); ok {
+		if n := w - utf8.RuneCountInString(out); n > 0 {
+			pad = strings.Repeat(" ", n)
+		}
+	}
+	if f.Flag('-') {
+		io.WriteString(f, out)
+		io.WriteString(f, pad)
+	} else {
+		io.WriteString(f, pad)
+		io.WriteString(f, out)
+	}
+}
@@ -0,0 +1,41 @@
+package render
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValueFormat(t *testing.T) {
+	type testStruct struct {
+		Name string
+		I    any
+	}
+	type myIntType int
+
+	v := &testStruct{Name: "foo", I: []int{1, 2}}
+	for _, tc := range []struct {
+		format string
+		in     any
+		exp    string
+	}{
+		{"%v", V(v), `{Name:"foo", I:{1, 2}}`},
+		{"%s", V(v), `{Name:"foo", I:{1, 2}}`},
+		{"%#v", V(v), `(*render.testStruct){Name:"foo", I:[]int{1, 2}}`},
+		{"%+v", V(v), "{\n\tName:\"foo\",\n\tI:{\n\t\t1,\n\t\t2,\n\t},\n}"},
+		{"%+#v", V(v), "(*render.testStruct){\n\tName:\"foo\",\n\tI:[]int{\n\t\t1,\n\t\t2,\n\t},\n}"},
+		{"%v", V(myIntType(4)), `4`},
+		{"%#v", V(myIntType(4)), `render.myIntType(4)`},
+		{"[%6v]", V(myIntType(4)), `[     4]`},
+		{"[%-6v]", V(myIntType(4)), `[4     ]`},
+		{"[%2v]", V([]int{1, 2}), `[{1, 2}]`},
+		{"%d", V(1), `%!d(render.Value=1)`},
+		{"%.3v", V([]float64{3.14159, 1e6}), `{3.14, 1e+06}`},
+		{"%.0v", V(2.5), `2`},
+		{"%#.2v", V(float32(0.125)), `0.12`},
+		{"%.2v", V(complex(1.234, 5.678)), `(1.2+5.7i)`},
+	} {
+		if act := fmt.Sprintf(tc.format, tc.in); act != tc.exp {
+			t.Errorf("[%s] did not match expectations:\nExpected: %s\nActual  : %s", tc.format, tc.exp, act)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package render
+
+import (
+	"io/fs"
+	"reflect"
+)
+
+var fileModeType = reflect.TypeOf(fs.FileMode(0))
+
+// fileModeText returns the permission string of value if it is an
+// fs.FileMode (or os.FileMode, its alias), e.g. "drwxr-xr-x". Like time.Month
+// values, these are described regardless of the Stringers option.
+func fileModeText(value reflect.Value) (string, bool) {
+	if value.Type() != fileModeType {
+		return "", false
+	}
+	return fs.FileMode(value.Uint()).String(), true
+}
@@ -0,0 +1,242 @@
+package render
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// writeGo writes v as a Go expression (see GoSyntax). If typed is set, the
+// expression has v's type; otherwise, its type is implied by the context,
+// such as a composite literal's element type, and untyped constants suffice.
+//
+// Values of OpaqueTypes, and struct fields redacted by Redact, are written as
+// the zero value of their type, followed by a comment holding their
+// placeholder.
+func writeGo(buf *bytes.Buffer, s *traverseState, v reflect.Value, typed bool, opts *RenderOptions) {
+	if !v.IsValid() {
+		buf.WriteString("nil")
+		return
+	}
+	t := v.Type()
+
+	if text, ok := opts.OpaqueTypes[t]; ok {
+		writeGoPlaceholder(buf, t, typed, text)
+		return
+	}
+
+	if pe := recursionPointer(v); pe != 0 {
+		if s = s.forkFor(pe); s == nil {
+			writeGoNil(buf, t, typed)
+			buf.WriteString(" /* recursive */")
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			writeGoNil(buf, t, typed)
+			return
+		}
+		switch t.Elem().Kind() {
+		case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+			if t.Elem() != timeType {
+				buf.WriteByte('&')
+				writeGo(buf, s, v.Elem(), true, opts)
+				return
+			}
+		}
+		// Only composite literals can have their address taken.
+		buf.WriteString("func() ")
+		buf.WriteString(t.String())
+		if t.Elem().Kind() == reflect.Interface {
+			// The value has its dynamic type; declare v with the interface type.
+			buf.WriteString(" { var v ")
+			buf.WriteString(t.Elem().String())
+			buf.WriteString(" = ")
+		} else {
+			buf.WriteString(" { v := ")
+		}
+		writeGo(buf, s, v.Elem(), true, opts)
+		buf.WriteString("; return &v }()")
+
+	case reflect.Interface:
+		if v.IsNil() {
+			writeGoNil(buf, t, typed)
+			return
+		}
+		writeGo(buf, s, v.Elem(), true, opts)
+
+	case reflect.Struct:
+		if t == timeType {
+			if tv, ok := exportedValue(v); ok {
+				writeGoTime(buf, tv.Interface().(time.Time))
+				return
+			}
+		}
+		buf.WriteString(t.String())
+		buf.WriteByte('{')
+		var unexported []string
+		written := 0
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				unexported = append(unexported, f.Name)
+				continue
+			}
+			if written > 0 {
+				buf.WriteString(", ")
+			}
+			written++
+			buf.WriteString(f.Name)
+			buf.WriteString(": ")
+			if opts.Redact && hasTagOption(f, "redact") {
+				writeGoPlaceholder(buf, f.Type, false, "<redacted>")
+				continue
+			}
+			writeGo(buf, s, v.Field(i), false, opts)
+		}
+		if len(unexported) > 0 {
+			if written > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString("/* unexported: ")
+			buf.WriteString(strings.Join(unexported, ", "))
+			buf.WriteString(" */")
+		}
+		buf.WriteByte('}')
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			writeGoNil(buf, t, typed)
+			return
+		}
+		buf.WriteString(t.String())
+		buf.WriteByte('{')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeGo(buf, s, v.Index(i), false, opts)
+		}
+		buf.WriteByte('}')
+
+	case reflect.Map:
+		if v.IsNil() {
+			writeGoNil(buf, t, typed)
+			return
+		}
+		buf.WriteString(t.String())
+		buf.WriteByte('{')
+		keys, values := sortedMapEntries(v)
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeGo(buf, s, k, false, opts)
+			buf.WriteString(": ")
+			writeGo(buf, s, values[i], false, opts)
+		}
+		buf.WriteByte('}')
+
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		writeGoNil(buf, t, typed)
+		if !v.IsNil() {
+			buf.WriteString(" /* not nil */")
+		}
+
+	default:
+		writeGoScalar(buf, v, typed)
+	}
+}
+
+// writeGoPlaceholder writes the zero value of type t, converted to t if typed
+// is set, followed by text in a comment.
+func writeGoPlaceholder(buf *bytes.Buffer, t reflect.Type, typed bool, text string) {
+	writeGo(buf, nil, reflect.Zero(t), typed, &RenderOptions{})
+	buf.WriteString(" /* ")
+	buf.WriteString(strings.ReplaceAll(text, "*/", "* /"))
+	buf.WriteString(" */")
+}
+
+// writeGoScalar writes the basic value v as a Go expression, converted to its
+// type if typed is set and its type isn't the default type of the constant.
+func writeGoScalar(buf *bytes.Buffer, v reflect.Value, typed bool) {
+	var lit string
+	switch v.Kind() {
+	case reflect.String:
+		lit = strconv.Quote(v.String())
+	case reflect.Bool:
+		lit = strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		lit = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		lit = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		lit = goFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		lit = "complex(" + goFloat(real(c)) + ", " + goFloat(imag(c)) + ")"
+	}
+
+	t := v.Type()
+	if strings.HasPrefix(lit, "math.") || strings.Contains(lit, "(math.") {
+		// Non-finite values aren't constants, so they only have the type of
+		// the context if it is float64 or complex128.
+		typed = typed || t != typeOfFloat && t != typeOfComplex128
+	}
+	if !typed || t == typeOfString || t == typeOfInt || t == reflect.TypeOf(true) {
+		buf.WriteString(lit)
+		return
+	}
+	buf.WriteString(t.String())
+	buf.WriteByte('(')
+	buf.WriteString(lit)
+	buf.WriteByte(')')
+}
+
+var typeOfComplex128 = reflect.TypeOf(complex128(0))
+
+// goFloat returns f as a Go expression.
+func goFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "math.NaN()"
+	case math.IsInf(f, 1):
+		return "math.Inf(1)"
+	case math.IsInf(f, -1):
+		return "math.Inf(-1)"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// writeGoNil writes a nil value of type t, converted to t if typed is set.
+func writeGoNil(buf *bytes.Buffer, t reflect.Type, typed bool) {
+	if !typed {
+		buf.WriteString("nil")
+		return
+	}
+	buf.WriteByte('(')
+	buf.WriteString(t.String())
+	buf.WriteString(")(nil)")
+}
+
+// writeGoTime writes tm as a call to time.Date.
+func writeGoTime(buf *bytes.Buffer, tm time.Time) {
+	loc := "time.UTC"
+	if tm.Location() != time.UTC {
+		name, offset := tm.Zone()
+		loc = "time.FixedZone(" + strconv.Quote(name) + ", " + strconv.Itoa(offset) + ")"
+	}
+	buf.WriteString("time.Date(")
+	for _, n := range []int{tm.Year(), int(tm.Month()), tm.Day(), tm.Hour(), tm.Minute(), tm.Second(), tm.Nanosecond()} {
+		buf.WriteString(strconv.Itoa(n))
+		buf.WriteString(", ")
+	}
+	buf.WriteString(loc)
+	buf.WriteByte(')')
+}
@@ -0,0 +1,111 @@
+package render
+
+import (
+	"go/parser"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func assertRendersGoLike(t *testing.T, name string, v any, exp string) {
+	t.Helper()
+	assertRendersGoWithLike(t, name, v, RenderOptions{}, exp)
+}
+
+func assertRendersGoWithLike(t *testing.T, name string, v any, opts RenderOptions, exp string) {
+	t.Helper()
+	opts.GoSyntax = true
+	act := RenderWith(v, opts)
+	if act != exp {
+		t.Errorf("[%s] did not match expectations:\nExpected: %s\nActual  : %s", name, exp, act)
+	}
+	if _, err := parser.ParseExpr(act); err != nil {
+		t.Errorf("[%s] is not a valid Go expression: %s: %v", name, act, err)
+	}
+}
+
+func TestRenderGoSyntax(t *testing.T) {
+	type level int
+	type inner struct {
+		Tags []string
+		m    map[string]int
+	}
+	type outer struct {
+		Name  string
+		Level level
+		Inner *inner
+		Any   any
+		Nil   *inner
+		Map   map[string]float64
+		Count *int
+		Pair  [2]bool
+		When  time.Time
+		id    int
+	}
+	n := 7
+	v := &outer{
+		Name:  "x",
+		Level: 2,
+		Inner: &inner{Tags: []string{"a"}, m: map[string]int{"k": 1}},
+		Any:   int8(3),
+		Map:   map[string]float64{"b": 1, "a": math.Inf(1)},
+		Count: &n,
+		When:  time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+	}
+
+	assertRendersGoLike(t, "Struct", v, `&render.outer{Name: "x", Level: 2, Inner: &render.inner{Tags: []string{"a"} /* unexported: m */}, `+
+		`Any: int8(3), Nil: nil, Map: map[string]float64{"a": math.Inf(1), "b": 1}, Count: func() *int { v := 7; return &v }(), `+
+		`Pair: [2]bool{false, false}, When: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC) /* unexported: id */}`)
+	assertRendersGoLike(t, "Scalars", []any{1, "s", true, 1.5, level(3), uint8(4), complex(1, -2), nil},
+		`[]interface {}{1, "s", true, float64(1.5), render.level(3), uint8(4), complex128(complex(1, -2)), nil}`)
+	assertRendersGoLike(t, "Typed nil", []*int(nil), `([]*int)(nil)`)
+	assertRendersGoLike(t, "Nil", nil, `nil`)
+	assertRendersGoLike(t, "Func", []func(){func() {}, nil}, `[]func(){nil /* not nil */, nil}`)
+}
+
+func TestRenderGoSyntaxNonFinite(t *testing.T) {
+	type ratio float64
+
+	assertRendersGoLike(t, "float64", []float64{math.NaN(), 1}, `[]float64{math.NaN(), 1}`)
+	assertRendersGoLike(t, "float32", []float32{float32(math.NaN()), float32(math.Inf(-1)), 1},
+		`[]float32{float32(math.NaN()), float32(math.Inf(-1)), 1}`)
+	assertRendersGoLike(t, "Named", []ratio{ratio(math.Inf(1))}, `[]render.ratio{render.ratio(math.Inf(1))}`)
+	assertRendersGoLike(t, "complex64", []complex64{complex(float32(math.NaN()), 0)},
+		`[]complex64{complex64(complex(math.NaN(), 0))}`)
+}
+
+func TestRenderGoSyntaxPlaceholders(t *testing.T) {
+	type secret struct{ key []byte }
+	type login struct {
+		User     string
+		Password string `render:"redact"`
+		Key      secret
+		Keys     []*secret
+	}
+	v := login{User: "ann", Password: "hunter2", Key: secret{[]byte("k")}, Keys: []*secret{{}}}
+	opts := RenderOptions{Redact: true, OpaqueTypes: map[reflect.Type]string{reflect.TypeOf(secret{}): "<key>"}}
+
+	assertRendersGoWithLike(t, "Placeholders", v, opts,
+		`render.login{User: "ann", Password: "" /* <redacted> */, Key: render.secret{/* unexported: key */} /* <key> */, `+
+			`Keys: []*render.secret{&render.secret{/* unexported: key */} /* <key> */}}`)
+	assertRendersGoLike(t, "Without options", v,
+		`render.login{User: "ann", Password: "hunter2", Key: render.secret{/* unexported: key */}, `+
+			`Keys: []*render.secret{&render.secret{/* unexported: key */}}}`)
+}
+
+func TestRenderGoSyntaxRecursion(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	n := &node{Name: "a"}
+	n.Next = n
+
+	assertRendersGoLike(t, "Cycle", n, `&render.node{Name: "a", Next: nil /* recursive */}`)
+
+	var x any
+	x = &x
+	assertRendersGoLike(t, "Pointer to itself", x,
+		`func() *interface {} { var v interface {} = (*interface {})(nil) /* recursive */; return &v }()`)
+}
@@ -0,0 +1,165 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// RenderJSON renders v as JSON. Unlike encoding/json, it includes unexported
+// fields, and it handles cycles.
+//
+// Structs are rendered as objects with their type in a "__type" member, and
+// pointers and interfaces as the values they point to. Values that would
+// recurse are rendered as {"__rec":"<type>"}. Maps are rendered as objects,
+// with keys that aren't strings rendered as in Render and converted to
+// strings. In maps keyed by an interface type, keys are rendered with their
+// type, or quoted if they are strings, so that keys of different types can't
+// collide. Keys that would still be rendered identically, such as NaN keys,
+// are told apart by a "#n" suffix. Values without a JSON equivalent, such as
+// channels, functions, and non-finite floats, are rendered as strings.
+func RenderJSON(v any) string {
+	return renderJSON(v, &RenderOptions{})
+}
+
+// renderJSON is like RenderJSON, rendering values without a JSON equivalent
+// and map keys with opts.
+func renderJSON(v any, opts *RenderOptions) string {
+	var buf bytes.Buffer
+	writeJSON(&buf, nil, addressable(reflect.ValueOf(v)), opts)
+	return buf.String()
+}
+
+// writeJSON writes v as JSON (see renderJSON). s is the traversal state of the
+// values containing v, used to detect cycles.
+func writeJSON(buf *bytes.Buffer, s *traverseState, v reflect.Value, opts *RenderOptions) {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return
+	}
+
+	if pe := recursionPointer(v); pe != 0 {
+		if s = s.forkFor(pe); s == nil {
+			buf.WriteString(`{"__rec":`)
+			writeJSONString(buf, v.Type().String())
+			buf.WriteByte('}')
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return
+		}
+		writeJSON(buf, s, v.Elem(), opts)
+
+	case reflect.Struct:
+		if v.Type() == timeType {
+			if t, ok := exportedValue(v); ok {
+				writeJSONString(buf, t.Interface().(time.Time).Format(time.RFC3339Nano))
+				return
+			}
+		}
+		buf.WriteString(`{"__type":`)
+		writeJSONString(buf, v.Type().String())
+		for i := 0; i < v.NumField(); i++ {
+			buf.WriteByte(',')
+			writeJSONString(buf, v.Type().Field(i).Name)
+			buf.WriteByte(':')
+			writeJSON(buf, s, v.Field(i), opts)
+		}
+		buf.WriteByte('}')
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			buf.WriteString("null")
+			return
+		}
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSON(buf, s, v.Index(i), opts)
+		}
+		buf.WriteByte(']')
+
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return
+		}
+		buf.WriteByte('{')
+		keys, values := sortedMapEntries(v)
+		seen := make(map[string]int, len(keys))
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key := jsonKey(k, opts)
+			if n := seen[key]; n > 0 {
+				// Only keys holding NaN, or of distinct types with the same name,
+				// are rendered identically. Such maps have no string keys, or
+				// quote them, so no other key can read like a suffixed one.
+				seen[key] = n + 1
+				key += "#" + strconv.Itoa(n+1)
+			} else {
+				seen[key] = 1
+			}
+			writeJSONString(buf, key)
+			buf.WriteByte(':')
+			writeJSON(buf, s, values[i], opts)
+		}
+		buf.WriteByte('}')
+
+	case reflect.String:
+		writeJSONString(buf, v.String())
+	case reflect.Bool:
+		buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			writeJSONString(buf, strconv.FormatFloat(f, 'g', -1, 64))
+		} else {
+			buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+		}
+
+	default:
+		writeJSONString(buf, renderedString(v, opts))
+	}
+}
+
+// jsonKey returns the string that the map key k is rendered as with opts. Keys
+// held in interfaces are rendered with their type, quoted if they are strings,
+// so that keys of different types can't collide.
+func jsonKey(k reflect.Value, opts *RenderOptions) string {
+	kr := renderer{opts: opts}
+	if k.Kind() == reflect.Interface {
+		if k.IsNil() {
+			return "nil"
+		}
+		k = k.Elem()
+		kr.forceType = k.Kind() != reflect.String
+		kr.render(nil, 0, k, false)
+		return kr.buf.String()
+	}
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	kr.render(nil, 0, k, true)
+	return kr.buf.String()
+}
+
+// writeJSONString writes s as a JSON string.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
@@ -0,0 +1,86 @@
+package render
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func assertRendersJSONLike(t *testing.T, name string, v any, exp string) {
+	t.Helper()
+	act := renderJSON(v, &RenderOptions{formatPointer: ptrPlaceholder})
+	if act != exp {
+		t.Errorf("[%s] did not match expectations:\nExpected: %s\nActual  : %s", name, exp, act)
+	}
+	if !json.Valid([]byte(act)) {
+		t.Errorf("[%s] is not valid JSON: %s", name, act)
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	type inner struct {
+		Tags []string
+		m    map[string]int
+	}
+	type outer struct {
+		Name   string
+		count  int
+		Inner  *inner
+		Any    any
+		Nil    *inner
+		When   time.Time
+		Ratio  float64
+		Values [2]bool
+	}
+
+	v := outer{
+		Name:   "x",
+		count:  3,
+		Inner:  &inner{[]string{"a"}, map[string]int{"k": 1}},
+		Any:    1.5,
+		When:   time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC),
+		Ratio:  math.Inf(1),
+		Values: [2]bool{true, false},
+	}
+	assertRendersJSONLike(t, "Struct", v,
+		`{"__type":"render.outer","Name":"x","count":3,`+
+			`"Inner":{"__type":"render.inner","Tags":["a"],"m":{"k":1}},`+
+			`"Any":1.5,"Nil":null,"When":"2000-01-02T03:04:05Z","Ratio":"+Inf","Values":[true,false]}`)
+	assertRendersJSONLike(t, "Nil", nil, `null`)
+	assertRendersJSONLike(t, "Nil slice", []int(nil), `null`)
+	assertRendersJSONLike(t, "Escaping", "a\"b\n", `"a\"b\n"`)
+	assertRendersJSONLike(t, "Channel", make(chan int), `"(chan int)(PTR)"`)
+}
+
+func TestRenderJSONCycles(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+
+	n := &node{Name: "a"}
+	n.Next = &node{"b", n}
+	assertRendersJSONLike(t, "Cyclic struct", n,
+		`{"__type":"render.node","Name":"a","Next":{"__type":"render.node","Name":"b","Next":{"__rec":"*render.node"}}}`)
+
+	m := map[string]any{}
+	m["self"] = m
+	assertRendersJSONLike(t, "Cyclic map", m, `{"self":{"__rec":"map[string]interface {}"}}`)
+
+	var x any
+	x = &x
+	assertRendersJSONLike(t, "Pointer to itself", x, `{"__rec":"*interface {}"}`)
+}
+
+func TestRenderJSONMapKeys(t *testing.T) {
+	type key struct{ A, B int }
+
+	assertRendersJSONLike(t, "Int keys", map[int]string{10: "b", -1: "a", 2: "c"}, `{"-1":"a","2":"c","10":"b"}`)
+	assertRendersJSONLike(t, "Struct keys", map[key]int{{1, 2}: 3}, `{"{A:1, B:2}":3}`)
+	assertRendersJSONLike(t, "Interface keys", map[any]int{"s": 1, 2: 2}, `{"int(2)":2,"\"s\"":1}`)
+	assertRendersJSONLike(t, "Colliding keys", map[any]int{"2": 1, 2: 2, nil: 3}, `{"nil":3,"int(2)":2,"\"2\"":1}`)
+	assertRendersJSONLike(t, "NaN keys", map[float64]int{math.NaN(): 1, math.NaN(): 1}, `{"NaN":1,"NaN#2":1}`)
+	assertRendersJSONLike(t, "Suffixed keys", map[any]int{math.NaN(): 1, math.NaN(): 2, "float64(NaN)#2": 3},
+		`{"float64(NaN)":1,"float64(NaN)#2":2,"\"float64(NaN)#2\"":3}`)
+}
@@ -0,0 +1,158 @@
+package render
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+var (
+	typeOfError         = reflect.TypeOf((*error)(nil)).Elem()
+	typeOfStringer      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	typeOfTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// methodText returns the quoted output of v's Error or String method, or the
+// output of its MarshalText method, when the corresponding option is enabled.
+// Error takes precedence over String, which takes precedence over MarshalText.
+// Wrapped errors are rendered as a chain (see errorText), and values failing
+// to marshal are rendered structurally.
+//
+// Methods with pointer receivers are used as well, on a copy of v if it isn't
+// addressable, so that a value renders the same whether it is found in a
+// struct field, a slice element, or a map value. Pointers and interfaces are
+// never called directly; their contents are examined when they are
+// dereferenced. Likewise, methods promoted from embedded interfaces aren't
+// used.
+func (r *renderer) methodText(v reflect.Value) (string, bool) {
+	if !r.opts.Errors && !r.opts.Stringers && !r.opts.UseTextMarshaler {
+		return "", false
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return "", false
+	}
+	if v.Type() == timeType || !v.CanInterface() {
+		return "", false
+	}
+
+	rt := reflect.PtrTo(v.Type())
+	switch {
+	case r.opts.Errors && rt.Implements(typeOfError) && !promotedFromInterface(v.Type(), "Error"):
+		return errorText(receiver(v, typeOfError).(error), 0), true
+	case r.opts.Stringers && rt.Implements(typeOfStringer) && !promotedFromInterface(v.Type(), "String") &&
+		!(r.opts.StringerLeafOnly && isContainer(v.Kind())):
+		return strconv.Quote(receiver(v, typeOfStringer).(fmt.Stringer).String()), true
+	case r.opts.UseTextMarshaler && rt.Implements(typeOfTextMarshaler) && !promotedFromInterface(v.Type(), "MarshalText"):
+		text, err := receiver(v, typeOfTextMarshaler).(encoding.TextMarshaler).MarshalText()
+		return string(text), err == nil
+	}
+	return "", false
+}
+
+// receiver returns v, or a pointer to it, implementing iface. If only the
+// pointer implements iface and v isn't addressable, a pointer to a copy of v
+// is returned.
+func receiver(v reflect.Value, iface reflect.Type) any {
+	switch {
+	case v.CanAddr():
+		return v.Addr().Interface()
+	case v.Type().Implements(iface):
+		return v.Interface()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Interface()
+}
+
+// isContainer returns true if values of kind k hold other values.
+func isContainer(k reflect.Kind) bool {
+	switch k {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		return true
+	}
+	return false
+}
+
+// promotedFromInterface returns true if t is a struct embedding an interface
+// with the method name, which is then presumably promoted from it. Such methods
+// aren't used, as the interface may be nil; the field is rendered instead.
+func promotedFromInterface(t reflect.Type, name string) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Type.Kind() == reflect.Interface {
+			if _, ok := f.Type.MethodByName(name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maxErrorChain bounds the number of wrapped errors rendered by errorText,
+// in case an error wraps itself.
+const maxErrorChain = 64
+
+// errorText renders the message of err, followed by those of the errors it
+// wraps: `"outer" <- "inner" <- "root"`. Errors wrapping several others, such
+// as those from errors.Join, render them as a list: `["a", "b"]`.
+//
+// Wrapping errors usually include the wrapped errors' messages in their own,
+// as in fmt.Errorf("outer: %w", err). Those are dropped from the message, as
+// they are shown in the chain anyway.
+func errorText(err error, depth int) string {
+	msg := err.Error()
+	if depth >= maxErrorChain {
+		return strconv.Quote(msg)
+	}
+
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		if inner := u.Unwrap(); inner != nil {
+			msg = strings.TrimSuffix(msg, ": "+inner.Error())
+			return strconv.Quote(msg) + " <- " + errorText(inner, depth+1)
+		}
+
+	case interface{ Unwrap() []error }:
+		var elems, msgs []string
+		for _, inner := range u.Unwrap() {
+			if inner != nil {
+				elems = append(elems, errorText(inner, depth+1))
+				msgs = append(msgs, inner.Error())
+			}
+		}
+		list := "[" + strings.Join(elems, ", ") + "]"
+		if msg == strings.Join(msgs, "\n") {
+			return list
+		}
+		return strconv.Quote(msg) + " <- " + list
+	}
+	return strconv.Quote(msg)
+}
+
+// funcName returns the name of the function held by the func value v, if
+// FuncNames is set and it can be resolved, e.g. "render.helper". Method
+// values, which are bound to their receiver, are marked as such, e.g.
+// "methodValue(render.T.String)".
+func (r *renderer) funcName(v reflect.Value) (string, bool) {
+	if !r.opts.FuncNames || v.Kind() != reflect.Func {
+		return "", false
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		return "", false
+	}
+	name := fn.Name()
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	if bound := strings.TrimSuffix(name, "-fm"); bound != name {
+		return "methodValue(" + bound + ")", true
+	}
+	return name, true
+}
@@ -0,0 +1,47 @@
+package render
+
+import (
+	"net"
+	"net/netip"
+	"reflect"
+)
+
+// netText returns the string form of v if it is one of the network address
+// types of the net and net/netip packages, which are otherwise rendered as
+// byte dumps or opaque structs.
+func netText(v reflect.Value) (string, bool) {
+	switch v.Type() {
+	case typeOfIP, typeOfIPNet, typeOfAddr, typeOfAddrPort, typeOfPrefix:
+	default:
+		return "", false
+	}
+	v, ok := exportedValue(v)
+	if !ok {
+		return "", false
+	}
+
+	switch x := v.Interface().(type) {
+	case net.IP:
+		if x == nil {
+			return "", false
+		}
+		return x.String(), true
+	case net.IPNet:
+		return x.String(), true
+	case netip.Addr:
+		return x.String(), true
+	case netip.AddrPort:
+		return x.String(), true
+	case netip.Prefix:
+		return x.String(), true
+	}
+	return "", false
+}
+
+var (
+	typeOfIP       = reflect.TypeOf(net.IP(nil))
+	typeOfIPNet    = reflect.TypeOf(net.IPNet{})
+	typeOfAddr     = reflect.TypeOf(netip.Addr{})
+	typeOfAddrPort = reflect.TypeOf(netip.AddrPort{})
+	typeOfPrefix   = reflect.TypeOf(netip.Prefix{})
+)
@@ -0,0 +1,466 @@
+// Copyright 2015 The Chromium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style license that can be
+// found in the LICENSE file.
+
+package render
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+)
+
+// RenderOptions customizes the output of RenderWith.
+//
+// The zero value produces the same output as Render.
+type RenderOptions struct {
+	// OmitZero skips struct fields that hold the zero value for their type.
+	OmitZero bool
+
+	// ChanCapacity includes the buffer capacity of channels in their type
+	// annotation, e.g. "(chan int, cap=4)".
+	ChanCapacity bool
+
+	// BytesAsString renders non-empty byte slices as a quoted string when they
+	// hold printable UTF-8 text, and as a hex dump otherwise, instead of
+	// listing every byte.
+	BytesAsString bool
+
+	// OmitTypePrefix drops type annotations, rendering values structurally
+	// (e.g. "{Name:"foo", I:nil}"). Named scalar types render as their bare
+	// value. The output may be ambiguous.
+	OmitTypePrefix bool
+
+	// Errors renders values implementing error using their Error method.
+	// Wrapped errors are rendered as a chain, e.g. `("outer" <- "root")`, and
+	// joined errors as a list, e.g. `(["a", "b"])`.
+	Errors bool
+
+	// Stringers renders values implementing fmt.Stringer using their String
+	// method. If a value implements both error and fmt.Stringer and Errors is
+	// also set, Error is used.
+	Stringers bool
+
+	// MinRunLength, if positive, collapses runs of at least this many
+	// consecutive identical slice or array elements into a single "elem xN"
+	// entry.
+	MinRunLength int
+
+	// TypeHandlers maps concrete types to functions that produce their
+	// rendered text. The text is used verbatim, wrapped with the value's type.
+	// Handlers take precedence over Errors and Stringers.
+	TypeHandlers map[reflect.Type]func(any) string
+
+	// MarkTypedNils annotates non-nil interface values that hold a nil
+	// pointer, map, slice, channel, or function, e.g.
+	// "any((*T)(nil) <TYPED-NIL>)".
+	MarkTypedNils bool
+
+	// ShowTags includes the raw struct tag after the names of tagged fields,
+	// e.g. `Name(json:"name"):"foo"`.
+	ShowTags bool
+
+	// TimeLayout, if set, formats time.Time values using this layout instead
+	// of their String method. This omits any monotonic clock reading.
+	TimeLayout string
+
+	// FlattenEmbedded renders the fields of embedded structs inline in their
+	// embedding struct, one level deep. Promoted fields whose names collide
+	// with another field keep their qualified "Embedded.Field" name.
+	FlattenEmbedded bool
+
+	// MaxTotalBytes, if positive, stops rendering once the output exceeds this
+	// many bytes. The output is then cut to this length and suffixed with
+	// "...<truncated>". In indented mode, the brackets left open by the cut are
+	// then closed on their own lines.
+	MaxTotalBytes int
+
+	// QualifyMapKeyTypes renders map keys of named types with their type, e.g.
+	// `render.myStringType("k")` rather than `"k"`. Complex keys are then
+	// rendered with their type too, e.g. `complex64(1+2i)`, to show their
+	// precision.
+	QualifyMapKeyTypes bool
+
+	// ShowInterfaceTypes wraps values held in interface-typed fields, elements,
+	// and map entries with the interface type, e.g.
+	// "io.Reader((*bytes.Buffer){...})".
+	ShowInterfaceTypes bool
+
+	// Indent, if set, renders each struct field, slice or array element, and
+	// map entry on its own line, indented by one Indent per nesting level and
+	// followed by a comma.
+	Indent string
+
+	// ByteAlias writes the uint8 type as "byte" wherever it appears in a type
+	// name, e.g. "[]byte(nil)" and "[4]byte{...}".
+	ByteAlias bool
+
+	// MapKVSep, if set, replaces the ":" written between map keys or struct
+	// field names and their values.
+	MapKVSep string
+
+	// ItemSep, if set, replaces the ", " written between struct fields, slice
+	// and array elements, and map entries. In indented mode, trailing spaces
+	// are trimmed from it.
+	ItemSep string
+
+	// UnexportedTimes renders unexported time.Time fields like exported ones,
+	// rather than dumping their internals. This is only possible for
+	// addressable fields; fields of map values, for example, are not.
+	UnexportedTimes bool
+
+	// ShowDynamicTypes renders values held in interfaces with their dynamic
+	// type, even builtin types that are normally rendered bare, e.g.
+	// "[]any{int(1), string("a")}".
+	ShowDynamicTypes bool
+
+	// EnumNames maps integer types to the names of their values, which are
+	// rendered in place of the number, e.g. `render.myIntType(FortyTwo)`.
+	// Values without a registered name are rendered as numbers.
+	EnumNames map[reflect.Type]map[int64]string
+
+	// ShowIndices prefixes slice and array elements with their index, like map
+	// keys, e.g. `[]string{0:"foo", 1:"bar"}`. A run collapsed by MinRunLength
+	// is prefixed with the index of its first element.
+	ShowIndices bool
+
+	// FlagNames maps integer types used as bit sets to the names of their
+	// flags. Values are rendered as the names of the flags they contain, e.g.
+	// `render.Flags(Read|Write)`, followed by any remaining bits in hex. Zero
+	// renders as the name registered for it, or as 0.
+	FlagNames map[reflect.Type]map[int64]string
+
+	// NoExponent renders floats in decimal notation, e.g. 1000000 rather than
+	// 1e+06, still using as many digits as needed to represent them exactly.
+	// It is equivalent to a FloatFormat of 'f'.
+	NoExponent bool
+
+	// Redact renders the values of struct fields tagged `render:"redact"` as
+	// "<redacted>", keeping only their type, e.g. `Password:string(<redacted>)`.
+	Redact bool
+
+	// IncludeFields, if not empty, limits the fields rendered for the
+	// outermost structs to those named, and their fields to those named by
+	// dotted paths such as "User.Name". Omitted fields are summarized by a
+	// trailing "...".
+	IncludeFields []string
+
+	// Color highlights types, strings, and recursion markers with ANSI escape
+	// codes. RenderTo ignores it unless it writes to a terminal.
+	Color bool
+
+	// ShowAddresses adds the address of pointers to their type, e.g.
+	// `(*render.T @0x000000c000012345){A:1}`, to show their identity along with
+	// the value they point to.
+	ShowAddresses bool
+
+	// ElideElemTypes omits the types of the elements of slices and arrays, when
+	// they are implied by the container's type, e.g. `[]*render.T{{A:1}}`
+	// rather than `[]*render.T{(*render.T){A:1}}`. Elements of interface type
+	// keep their dynamic types.
+	ElideElemTypes bool
+
+	// SQLNulls renders the database/sql null types, such as sql.NullString, and
+	// other structs made of a value and a "Valid bool" field, as their value if
+	// it is valid, e.g. `sql.NullString("x")`, and as `sql.NullString(NULL)`
+	// if it is not.
+	SQLNulls bool
+
+	// SortStructFields renders struct fields in the alphabetical order of their
+	// names, ignoring case, rather than in declaration order.
+	SortStructFields bool
+
+	// TimeUTC converts times to UTC before rendering them, so that the output
+	// doesn't depend on the local time zone.
+	TimeUTC bool
+
+	// MaxNodes, if positive, stops rendering once this many values have been
+	// rendered, writing "...<node limit>" in place of the next one. This bounds
+	// the time spent rendering large graphs of small values.
+	MaxNodes int
+
+	// MapOrder sets the order in which map entries are rendered.
+	MapOrder MapOrder
+
+	// HeadTail, if positive, renders only the first and last HeadTail elements
+	// of longer slices and arrays, with the number of elements left out in
+	// between, e.g. `[]int{0, 1, ...(+996 more of 1000), 998, 999}`. It has no
+	// effect with MinRunLength.
+	HeadTail int
+
+	// ShowMethods appends a comment listing the exported methods of the
+	// rendered value's type, including those of its pointer type, e.g.
+	// `render.T{A:1} /* methods: Error, String */`. Nested values aren't
+	// annotated.
+	ShowMethods bool
+
+	// StringerLeafOnly limits Stringers to values that don't hold other
+	// values, so that slices, arrays, maps, and structs implementing
+	// fmt.Stringer are still rendered structurally.
+	StringerLeafOnly bool
+
+	// FieldFilter, if set, is called for each struct field to be rendered,
+	// with its path from the rendered value (as in RenderHighlight), and
+	// returns whether to render it. Fields it rejects are omitted.
+	FieldFilter func(path string, field reflect.StructField, value reflect.Value) bool
+
+	// RecursionPaths replaces the type in recursion markers with the path of
+	// the value they refer back to, as in RenderHighlight and starting with
+	// "." for the rendered value itself, e.g. `<REC -> .Child.Parent>`.
+	RecursionPaths bool
+
+	// HumanDurations renders time.Duration values in human-readable form, e.g.
+	// `time.Duration(1m30s)`, including as map keys, which are still ordered
+	// by length.
+	HumanDurations bool
+
+	// CycleIDs tags the values that cycles lead back to with ids, e.g. "#1",
+	// and renders the cycles as references to them, e.g. `<CYCLE #1>`, rather
+	// than as recursion markers. It takes precedence over RecursionPaths.
+	CycleIDs bool
+
+	// UUIDs renders arrays of 16 bytes, whatever their type, as canonical UUID
+	// strings, e.g. `[16]uint8(550e8400-e29b-41d4-a716-446655440000)`.
+	UUIDs bool
+
+	// FloatFormat, if set, is the format used for floats and for the parts of
+	// complex numbers: 'g', 'f', or 'e', as in strconv.FormatFloat. It
+	// defaults to 'g'.
+	FloatFormat byte
+
+	// FloatPrecision, if positive, is the number of digits used for floats and
+	// for the parts of complex numbers, as in strconv.FormatFloat. Otherwise,
+	// as many digits as needed to represent them exactly are used.
+	FloatPrecision int
+
+	// MaxSliceLen, if positive, renders only the first MaxSliceLen elements of
+	// longer slices and arrays, followed by the number of elements left out
+	// and the total, e.g. `[]int{0, 1, ...(+998 more of 1000)}`. It has no
+	// effect with HeadTail or MinRunLength.
+	MaxSliceLen int
+
+	// MaxMapLen, if positive, renders only the first MaxMapLen entries of
+	// larger maps, followed by the number of entries left out and the total,
+	// e.g. `map[string]int{"a":1, ...(+149 more of 150)}`.
+	MaxMapLen int
+
+	// MaxLinkedLen, if positive, stops following chains of pointers of the same
+	// type, such as linked lists, after MaxLinkedLen of them, writing
+	// "...(chain continues)" in place of the next one.
+	MaxLinkedLen int
+
+	// OnNode, if set, is called for each value as it is rendered, in order,
+	// with its path from the rendered value (as in RenderHighlight). It is
+	// also called for the value at which the output is truncated, and for the
+	// elements left out by HeadTail, MaxSliceLen, and MaxMapLen, though not
+	// for their contents. Map keys aren't reported.
+	OnNode func(path string, v reflect.Value)
+
+	// ShortAnonStructs writes anonymous struct types as "struct{...}", and
+	// renders the names of their fields instead, e.g.
+	// `[]struct{...}{{a:1, b:2}}`.
+	ShortAnonStructs bool
+
+	// MaxKeyLen, if positive, cuts map keys whose rendering is longer than
+	// MaxKeyLen bytes to that length, followed by "...". Entries are still
+	// ordered by their whole keys.
+	MaxKeyLen int
+
+	// TrueToken, FalseToken, and NilToken, if set, replace the "true",
+	// "false", and "nil" tokens wherever they are written, e.g. to render
+	// `map[bool]*int{False:(*int)(Null), True:(*int)(Null)}`.
+	TrueToken, FalseToken, NilToken string
+
+	// FullPkgPath qualifies named types with the import path of their package,
+	// e.g. `net/url.Values{}`, rather than with its name only.
+	FullPkgPath bool
+
+	// ShortPkgNames qualifies named types with the last element of their
+	// package's import path, e.g. `yaml.v3.Node` for gopkg.in/yaml.v3, rather
+	// than with the package's name, which usually matches it. FullPkgPath
+	// takes precedence.
+	ShortPkgNames bool
+
+	// ShowCap follows non-nil slices with their length and capacity, e.g.
+	// `[]int{1, 2, 3}(len=3,cap=8)`. Nil slices are still rendered as nil.
+	ShowCap bool
+
+	// SkipProtoInternals omits the unexported bookkeeping fields of generated
+	// protobuf messages: those named state, sizeCache, or unknownFields, and
+	// those of types from google.golang.org/protobuf/internal packages.
+	SkipProtoInternals bool
+
+	// MapKeyLess, if set, orders the entries of every map rendered, replacing
+	// the default order of SortedMapKeys. Keys it considers equal keep their
+	// default order. It has no effect if MapOrder is Unsorted.
+	MapKeyLess func(a, b reflect.Value) bool
+
+	// UnquotedKeys writes string map keys without quotes, e.g.
+	// `map[string]int{foo:1}`. Keys that would be ambiguous without quotes,
+	// such as empty strings, strings with spaces or punctuation, and strings
+	// reading as numbers, are still quoted.
+	UnquotedKeys bool
+
+	// UnquotedValues writes strings other than map keys without quotes, like
+	// UnquotedKeys does for keys.
+	UnquotedValues bool
+
+	// Header prepends a line summarizing the rendered value: its type, how
+	// deeply nested it is, and how many values it holds, e.g.
+	// "// *render.T, depth=3, nodes=12".
+	Header bool
+
+	// CommonElemTypes appends a comment naming the dynamic type shared by all
+	// elements of an interface slice or array, e.g. `[]any{1, 2} /* all int */`.
+	// Slices holding nil or values of different types aren't annotated.
+	CommonElemTypes bool
+
+	// CompressMapPaths joins the keys of nested maps with string keys into
+	// dotted keys, as long as each nested map has a single entry, e.g.
+	// `map[string]any{"a.b.c":1}` rather than
+	// `map[string]any{"a":map[string]any{"b":map[string]any{"c":1}}}`.
+	CompressMapPaths bool
+
+	// MaxDepth, if positive, limits how deeply structs, slices, arrays, and
+	// maps are nested. Those nested deeper are rendered as `{...}`, with their
+	// type.
+	MaxDepth int
+
+	// MaxStringLen, if positive, cuts strings longer than this many runes,
+	// marking the cut with a trailing "...", e.g. `"abc"...`.
+	MaxStringLen int
+
+	// PointerKeyIDs numbers the keys of maps keyed by pointers, in address
+	// order, writing the number along with the value pointed to, e.g.
+	// `map[*int]string{(*int @#1)(5):"a", (*int @#2)(5):"b"}`. This tells
+	// apart keys pointing to equal values, without showing their addresses.
+	PointerKeyIDs bool
+
+	// Defaults, if set to a struct or a pointer to one, omits the fields of
+	// structs of the same type that are deeply equal to those of Defaults, so
+	// that only the fields set differently are rendered. Structs of other
+	// types are rendered in full.
+	Defaults any
+
+	// TypePrefixKinds, if not nil, limits type prefixes to values of the kinds
+	// it holds. Values of other kinds are rendered as with OmitTypePrefix. The
+	// prefix of a pointer is that of the value it points to, e.g. with only
+	// reflect.Struct, `(*render.T){A:1}` but `[]int{1}` renders as `{1}`.
+	TypePrefixKinds map[reflect.Kind]bool
+
+	// GoSyntax renders values as Go expressions that can be pasted into code,
+	// e.g. `&render.T{A: 1, B: []string{"x"}}`. Values that can't be written
+	// as literals are replaced with nil and a comment: recursive pointers,
+	// and non-nil channels and funcs. Unexported fields are listed in a
+	// comment rather than set. Values of OpaqueTypes and fields redacted by
+	// Redact are replaced with their zero value and a comment holding their
+	// placeholder. All other options are ignored.
+	GoSyntax bool
+
+	// NumericStringKeys orders the entries of maps with string keys by the
+	// numbers the keys hold, if they all hold decimal numbers, e.g.
+	// `map[string]int{"1":1, "2":2, "10":10}`. Maps with other keys are
+	// ordered as usual.
+	NumericStringKeys bool
+
+	// InlineWrappers renders named structs with a single field like named
+	// scalars, e.g. `render.Celsius(21.5)` rather than `render.Celsius{v:21.5}`.
+	InlineWrappers bool
+
+	// ShowNumericKinds renders numbers of builtin types with their type, e.g.
+	// `render.T{N:int64(42), F:float64(1)}` rather than `render.T{N:42, F:1}`.
+	// Numbers whose type is implied by their container, as in `[]int64{42}`,
+	// are still rendered bare.
+	ShowNumericKinds bool
+
+	// UseTextMarshaler renders values implementing encoding.TextMarshaler
+	// using their MarshalText method, e.g. `netip.Prefix(10.0.0.0/8)`. Values
+	// failing to marshal are rendered as usual. Errors and Stringers take
+	// precedence.
+	UseTextMarshaler bool
+
+	// MaxFields, if positive, renders only the first MaxFields fields of
+	// structs, in the order set by SortStructFields, followed by the number of
+	// fields left out, e.g. `render.T{A:1, B:2, ...(+8 more fields)}`. Fields
+	// omitted by other options aren't counted.
+	MaxFields int
+
+	// PathComments ends each line of indented output (see Indent) holding an
+	// element with a comment giving the element's path, e.g.
+	// `Name:"foo", // .User.Name`. It has no effect unless Indent is set.
+	PathComments bool
+
+	// BoolAsInt renders booleans as 1 and 0 rather than true and false. Map
+	// keys are still ordered false (0) first. TrueToken and FalseToken take
+	// precedence.
+	BoolAsInt bool
+
+	// FuncNames renders funcs by the name of the function they hold rather
+	// than by address, e.g. `(func())(render.helper)`. Method values, bound to
+	// their receiver, are marked as such, e.g.
+	// `(func() string)(methodValue(render.T.String))`.
+	FuncNames bool
+
+	// WrapAt, if positive, keeps containers on a single line in indented
+	// output (see Indent) as long as that line ends within WrapAt columns.
+	// Those that don't fit are wrapped, one element per line, with their
+	// elements given the same chance. Long scalars, such as strings, may still
+	// overflow unless MaxStringLen is set.
+	WrapAt int
+
+	// Locker, if set, is held for the duration of the render, so that a value
+	// shared with other goroutines is read consistently. The value should be
+	// passed by pointer, as passing it by value copies it before the lock is
+	// taken.
+	Locker sync.Locker
+
+	// StringEscaper, if set, escapes the contents of strings, including map
+	// keys, before they are quoted, e.g. for output embedded in HTML.
+	StringEscaper func(string) string
+
+	// OpaqueTypes maps types whose values are never to be rendered, such as
+	// private keys or large caches, to a placeholder written in their place,
+	// e.g. `render.key(<private key>)`. It takes precedence over all other
+	// options.
+	OpaqueTypes map[reflect.Type]string
+
+	// RecursionKinds includes the kind of the value in recursion markers, so
+	// that they are unambiguous for unnamed types, e.g.
+	// `<REC(map, map[string]any)>`.
+	RecursionKinds bool
+
+	// formatPointer, if set, writes pointer values in place of their address,
+	// so that the test suite can have deterministic pointer values in its
+	// expectations.
+	formatPointer func(buf *bytes.Buffer, p uintptr)
+}
+
+// SafeRenderOptions returns options suited to rendering untrusted values, such
+// as for logging: nesting, string, collection, and output lengths are bounded,
+// and fields tagged `render:"redact"` are redacted. Panics raised while
+// rendering, such as by String methods, are recovered from regardless of
+// options.
+func SafeRenderOptions() RenderOptions {
+	return RenderOptions{
+		MaxDepth:      16,
+		MaxStringLen:  1024,
+		MaxSliceLen:   100,
+		MaxMapLen:     100,
+		MaxNodes:      10000,
+		MaxTotalBytes: 64 << 10,
+		Redact:        true,
+	}
+}
+
+// MapOrder is the order in which map entries are rendered.
+type MapOrder int
+
+const (
+	// Sorted renders map entries ordered by key (see SortedMapKeys), so that
+	// the output is reproducible.
+	Sorted MapOrder = iota
+
+	// Unsorted renders map entries in Go's map iteration order, which varies
+	// between renders.
+	Unsorted
+)
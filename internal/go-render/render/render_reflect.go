@@ -0,0 +1,65 @@
+package render
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+var (
+	typeOfReflectValue = reflect.TypeOf(reflect.Value{})
+	typeOfReflectType  = reflect.TypeOf((*reflect.Type)(nil)).Elem()
+
+	// typeOfRType is the concrete type implementing reflect.Type.
+	typeOfRType = reflect.TypeOf(typeOfReflectValue)
+)
+
+// renderReflectValue renders a reflect.Value as the value that it holds. An
+// invalid reflect.Value renders as "<invalid>".
+func (r *renderer) renderReflectValue(s *traverseState, ptrs int, v reflect.Value, implicit bool) {
+	inner := v.Interface().(reflect.Value)
+	if !inner.IsValid() {
+		r.writeToken(TokenMarker, "<invalid>")
+		return
+	}
+
+	if implicit {
+		r.render(s, 0, inner, false)
+		return
+	}
+	r.writeType(ptrs, v.Type())
+	r.openBracket('(', ')')
+	r.render(s, 0, inner, false)
+	r.closeBracket(')')
+}
+
+// reflectTypeText returns the name of the type held by v, if v is a non-nil
+// reflect.Type.
+func reflectTypeText(v reflect.Value) (string, bool) {
+	switch v.Type() {
+	case typeOfReflectType:
+		if v.IsNil() {
+			return "", false
+		}
+	case typeOfRType:
+	default:
+		return "", false
+	}
+	v, ok := exportedValue(v)
+	if !ok {
+		return "", false
+	}
+	return v.Interface().(reflect.Type).String(), true
+}
+
+// exportedValue returns a view of v that can be used with Interface, even if v
+// was obtained through unexported struct fields. This is only possible if v is
+// addressable.
+func exportedValue(v reflect.Value) (reflect.Value, bool) {
+	if v.CanInterface() {
+		return v, true
+	}
+	if !v.CanAddr() {
+		return v, false
+	}
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem(), true
+}
@@ -0,0 +1,61 @@
+package render
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+)
+
+// maxPooledBuffer is the capacity above which a Renderer's output buffer is
+// dropped rather than reused, so that one huge value doesn't pin its memory.
+const maxPooledBuffer = 64 << 10
+
+// Renderer renders values with a fixed set of options, reusing its internal
+// state between calls. This saves allocations when rendering many values.
+//
+// The zero Renderer renders like Render. A Renderer is safe for concurrent
+// use, as long as its Options aren't modified while it is in use.
+type Renderer struct {
+	// Options customizes the rendered output, as in RenderWith.
+	Options RenderOptions
+
+	pool sync.Pool
+}
+
+// defaultRenderer backs Render.
+var defaultRenderer Renderer
+
+// Render renders v with the Renderer's options. Each call is independent of
+// the previous ones: no values are reported as recursive or truncated because
+// of earlier calls.
+func (rr *Renderer) Render(v any) string {
+	r := rr.get(&rr.Options)
+	r.run(reflect.ValueOf(v))
+	s := r.buf.String()
+	rr.put(r)
+	return s
+}
+
+// get returns a renderer from the pool, reset for rendering with opts.
+func (rr *Renderer) get(opts *RenderOptions) *renderer {
+	r, _ := rr.pool.Get().(*renderer)
+	if r == nil {
+		r = &renderer{}
+	}
+	r.reset(opts)
+	return r
+}
+
+// put returns r to the pool, unless its buffer has grown too large to keep.
+func (rr *Renderer) put(r *renderer) {
+	if r.buf.Cap() <= maxPooledBuffer {
+		r.opts = nil
+		rr.pool.Put(r)
+	}
+}
+
+// reset prepares r for rendering with opts, keeping its buffer's storage.
+func (r *renderer) reset(opts *RenderOptions) {
+	r.buf.Reset()
+	*r = renderer{opts: opts, buf: *bytes.NewBuffer(r.buf.Bytes())}
+}
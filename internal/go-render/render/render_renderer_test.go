@@ -0,0 +1,70 @@
+package render
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRenderer(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+
+	var zero Renderer
+	v := map[string][]int{"a": {1, 2}, "b": nil}
+	if act, exp := zero.Render(v), Render(v); act != exp {
+		t.Errorf("zero Renderer:\nExpected: %s\nActual  : %s", exp, act)
+	}
+
+	r := Renderer{Options: RenderOptions{OmitTypePrefix: true}}
+	if act, exp := r.Render(v), `{"a":{1, 2}, "b":nil}`; act != exp {
+		t.Errorf("options:\nExpected: %s\nActual  : %s", exp, act)
+	}
+
+	// Values seen in one call must not be reported as recursive in the next.
+	n := &node{Name: "loop"}
+	n.Next = n
+	exp := `(*render.node){Name:"loop", Next:<REC(*render.node)>}`
+	for i := 0; i < 3; i++ {
+		if act := zero.Render(n); act != exp {
+			t.Errorf("call %d:\nExpected: %s\nActual  : %s", i, exp, act)
+		}
+	}
+	if act, exp := zero.Render([]*node{n.Next}), `[]*render.node{(*render.node){Name:"loop", Next:<REC(*render.node)>}}`; act != exp {
+		t.Errorf("after recursion:\nExpected: %s\nActual  : %s", exp, act)
+	}
+}
+
+func TestRendererResetsTruncation(t *testing.T) {
+	r := Renderer{Options: RenderOptions{MaxTotalBytes: 20, Indent: "\t"}}
+
+	long := []string{strings.Repeat("x", 100)}
+	if act := r.Render(long); !strings.HasSuffix(act, "...<truncated>\n}") {
+		t.Errorf("expected truncated output, got: %s", act)
+	}
+	if act, exp := r.Render([]int{1}), "[]int{\n\t1,\n}"; act != exp {
+		t.Errorf("after truncation:\nExpected: %s\nActual  : %s", exp, act)
+	}
+}
+
+func TestRendererConcurrentUse(t *testing.T) {
+	var r Renderer
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v := []int{i, i, i}
+			exp := RenderWith(v, RenderOptions{})
+			for j := 0; j < 100; j++ {
+				if act := r.Render(v); act != exp {
+					t.Errorf("Expected: %s\nActual  : %s", exp, act)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
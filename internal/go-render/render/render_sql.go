@@ -0,0 +1,41 @@
+package render
+
+import "reflect"
+
+// nullValueField returns the index of the value field of v, if v has the
+// shape of the database/sql null types, such as sql.NullString: a struct of
+// two fields, one of which is "Valid bool".
+func nullValueField(v reflect.Value) (int, bool) {
+	t := v.Type()
+	if t.Kind() != reflect.Struct || t.NumField() != 2 {
+		return 0, false
+	}
+	for i := 0; i < 2; i++ {
+		if f := t.Field(i); f.Name == "Valid" && f.Type.Kind() == reflect.Bool {
+			return 1 - i, true
+		}
+	}
+	return 0, false
+}
+
+// renderSQLNull renders v as its value if it is valid, or as NULL if it is
+// not, if v is a database/sql null type. It returns false otherwise.
+func (r *renderer) renderSQLNull(s *traverseState, ptrs int, v reflect.Value, implicit bool) bool {
+	i, ok := nullValueField(v)
+	if !ok {
+		return false
+	}
+	if !implicit {
+		r.writeType(ptrs, v.Type())
+		r.openBracket('(', ')')
+	}
+	if v.Field(1 - i).Bool() {
+		r.render(s, 0, v.Field(i), true)
+	} else {
+		r.writeToken(TokenLiteral, "NULL")
+	}
+	if !implicit {
+		r.closeBracket(')')
+	}
+	return true
+}
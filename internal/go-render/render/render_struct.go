@@ -0,0 +1,221 @@
+package render
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// structField is a single field to be rendered as part of a struct.
+type structField struct {
+	// name is the name that the field is rendered with.
+	name  string
+	field reflect.StructField
+	value reflect.Value
+
+	// anon is true if the field's name and type are implied by its anonymous
+	// struct type, and so aren't rendered.
+	anon bool
+	// embeddedIn is the name of the embedded field that this field was
+	// flattened out of, if any.
+	embeddedIn string
+}
+
+// structFields returns the fields of the struct v in the order they should be
+// rendered.
+func (r *renderer) structFields(v reflect.Value) []structField {
+	vt := v.Type()
+	structAnon := vt.Name() == "" && !r.opts.ShortAnonStructs
+	fields := make([]structField, 0, vt.NumField())
+	flattened := false
+	for i := 0; i < vt.NumField(); i++ {
+		f := vt.Field(i)
+		if r.opts.SkipProtoInternals && isProtoInternal(f) {
+			continue
+		}
+		if r.opts.FlattenEmbedded && f.Anonymous && f.Type.Kind() == reflect.Struct && f.Type != timeType {
+			ev := v.Field(i)
+			for j := 0; j < f.Type.NumField(); j++ {
+				fields = append(fields, structField{
+					name:       f.Type.Field(j).Name,
+					field:      f.Type.Field(j),
+					value:      ev.Field(j),
+					embeddedIn: f.Name,
+				})
+			}
+			flattened = true
+			continue
+		}
+
+		fields = append(fields, structField{
+			name:  f.Name,
+			field: f,
+			value: v.Field(i),
+			anon:  structAnon && isAnonType(f.Type),
+		})
+	}
+
+	if flattened {
+		// Flattened fields whose names collide with any other field keep their
+		// qualified name.
+		counts := make(map[string]int, len(fields))
+		for _, f := range fields {
+			counts[f.name]++
+		}
+		for i, f := range fields {
+			if f.embeddedIn != "" && counts[f.name] > 1 {
+				fields[i].name = f.embeddedIn + "." + f.name
+			}
+		}
+	}
+
+	if r.opts.SortStructFields {
+		sort.SliceStable(fields, func(i, j int) bool {
+			a, b := fields[i].name, fields[j].name
+			if la, lb := strings.ToLower(a), strings.ToLower(b); la != lb {
+				return la < lb
+			}
+			return a < b
+		})
+	}
+	return fields
+}
+
+// skipsField returns true if the field f of a struct of type t is left out by
+// OmitZero, Defaults, or FieldFilter.
+func (r *renderer) skipsField(t reflect.Type, f structField) bool {
+	switch {
+	case r.opts.OmitZero && f.value.IsZero():
+		return true
+	case r.opts.Defaults != nil && r.isDefault(t, f):
+		return true
+	case r.opts.FieldFilter != nil && !r.opts.FieldFilter(joinPath(r.path, f.name), f.field, f.value):
+		return true
+	}
+	return false
+}
+
+// hasTagOption returns true if the `render` struct tag of f includes the
+// comma-separated option opt, as in `render:"redact"`.
+func hasTagOption(f reflect.StructField, opt string) bool {
+	for _, o := range strings.Split(f.Tag.Get("render"), ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// writeRedacted writes a placeholder in place of the value v, keeping its type.
+func (r *renderer) writeRedacted(v reflect.Value) {
+	ptrs, t := 0, v.Type()
+	for t.Kind() == reflect.Ptr {
+		ptrs++
+		t = t.Elem()
+	}
+	r.writeWrappedToken(ptrs, t, false, "<redacted>", TokenMarker)
+}
+
+// includedField returns whether the field name is included by the
+// IncludeFields paths in include, and the paths that then apply to its value.
+// A nil include includes every field, as does a path naming the field itself.
+func includedField(include []string, name string) ([]string, bool) {
+	if include == nil {
+		return nil, true
+	}
+	var sub []string
+	for _, path := range include {
+		if path == name {
+			return nil, true
+		}
+		if strings.HasPrefix(path, name+".") {
+			sub = append(sub, path[len(name)+1:])
+		}
+	}
+	return sub, sub != nil
+}
+
+// protoInternalFields holds the names of the bookkeeping fields of generated
+// protobuf messages.
+var protoInternalFields = map[string]bool{
+	"state":         true,
+	"sizeCache":     true,
+	"unknownFields": true,
+}
+
+// isProtoInternal returns true if f is a bookkeeping field of a generated
+// protobuf message, rather than one of its fields.
+func isProtoInternal(f reflect.StructField) bool {
+	if f.IsExported() {
+		return false
+	}
+	t := f.Type
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return protoInternalFields[f.Name] || strings.HasPrefix(t.PkgPath(), "google.golang.org/protobuf/internal/")
+}
+
+// isDefault returns true if the field f of a struct of type t equals the same
+// field of Defaults, which must then be a struct of type t, or a pointer to
+// one. Fields that can't be compared, such as unexported fields of values that
+// aren't addressable, are never considered defaults.
+func (r *renderer) isDefault(t reflect.Type, f structField) bool {
+	if !r.defaults.IsValid() {
+		d := reflect.ValueOf(r.opts.Defaults)
+		for d.Kind() == reflect.Ptr && !d.IsNil() {
+			d = d.Elem()
+		}
+		r.defaults = addressable(d)
+	}
+	d := r.defaults
+	if d.Type() != t {
+		return false
+	}
+	if f.embeddedIn != "" {
+		d = d.FieldByName(f.embeddedIn)
+	}
+	dv, ok := exportedValue(d.Field(f.field.Index[0]))
+	if !ok {
+		return false
+	}
+	v, ok := exportedValue(f.value)
+	if !ok {
+		return false
+	}
+	return reflect.DeepEqual(v.Interface(), dv.Interface())
+}
+
+// renderWrapper renders v like a named scalar, as its type followed by its
+// field in parentheses, e.g. `render.Celsius(21.5)`, if v is a named struct
+// with a single field to be rendered. It returns false otherwise, including
+// if the field is left out or redacted, so that v is rendered as a struct.
+func (r *renderer) renderWrapper(s *traverseState, ptrs int, v reflect.Value, implicit bool) bool {
+	vt := v.Type()
+	if vt.Name() == "" || vt.NumField() != 1 || vt == timeType {
+		return false
+	}
+	fields := r.structFields(v)
+	if len(fields) != 1 {
+		return false
+	}
+	f := fields[0]
+	fieldInclude, ok := includedField(r.include, f.name)
+	if !ok || r.skipsField(vt, f) || r.opts.Redact && hasTagOption(f.field, "redact") {
+		return false
+	}
+
+	if !implicit {
+		r.writeType(ptrs, vt)
+		r.openBracket('(', ')')
+	}
+	include := r.include
+	r.include = fieldInclude
+	parent := r.enterPath(f.name)
+	r.render(s, 0, f.value, false)
+	r.include, r.path = include, parent
+	if !implicit {
+		r.closeBracket(')')
+	}
+	return true
+}
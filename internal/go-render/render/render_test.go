@@ -6,24 +6,45 @@ package render
 
 import (
 	"bytes"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
 	"reflect"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+	"unsafe"
 )
 
-func init() {
-	// For testing purposes, pointers will render as "PTR" so that they are
-	// deterministic.
-	renderPointer = func(buf *bytes.Buffer, p uintptr) {
-		buf.WriteString("PTR")
-	}
+// ptrPlaceholder renders pointers as "PTR" so that they are deterministic.
+func ptrPlaceholder(buf *bytes.Buffer, p uintptr) {
+	buf.WriteString("PTR")
 }
 
 func assertRendersLike(t *testing.T, name string, v any, exp string) {
-	act := Render(v)
+	act := RenderWith(v, RenderOptions{formatPointer: ptrPlaceholder})
+	if act != exp {
+		_, _, line, _ := runtime.Caller(1)
+		t.Errorf("On line #%d, [%s] did not match expectations:\nExpected: %s\nActual  : %s\n", line, name, exp, act)
+	}
+}
+
+func assertRendersWithLike(t *testing.T, name string, v any, opts RenderOptions, exp string) {
+	opts.formatPointer = ptrPlaceholder
+	act := RenderWith(v, opts)
 	if act != exp {
 		_, _, line, _ := runtime.Caller(1)
 		t.Errorf("On line #%d, [%s] did not match expectations:\nExpected: %s\nActual  : %s\n", line, name, exp, act)
@@ -100,6 +121,8 @@ func TestRenderList(t *testing.T) {
 		}, `map[string]bool{"bar":false, "foo":true}`},
 		{map[int]string{1: "foo", 2: "bar"}, `map[int]string{1:"foo", 2:"bar"}`},
 		{uint32(1337), `1337`},
+		{uintptr(0xc0ffee), `uintptr(0xc0ffee)`},
+		{[]uintptr{1, 255}, `[]uintptr{0x1, 0xff}`},
 		{3.14, `3.14`},
 		{complex(3, 0.14), `(3+0.14i)`},
 		{&s0, `(*string)("string0")`},
@@ -134,6 +157,22 @@ func TestRenderRecursiveArray(t *testing.T) {
 		`(*[2]any){<REC(*[2]any)>, <REC(*[2]any)>}`)
 }
 
+func TestRenderInterfaceArray(t *testing.T) {
+	type point struct{ X int }
+	var a [5]any
+	a[0] = point{1}
+	a[1] = &point{2}
+	a[2] = 3
+	a[3] = &a
+
+	assertRendersLike(t, "Array", &a,
+		`(*[5]any){render.point{X:1}, (*render.point){X:2}, 3, <REC(*[5]any)>, any(nil)}`)
+	assertRendersLike(t, "Slice", a[:],
+		`[]any{render.point{X:1}, (*render.point){X:2}, 3, <REC(*[5]any)>, any(nil)}`)
+	assertRendersLike(t, "Array value", a,
+		`[5]any{render.point{X:1}, (*render.point){X:2}, 3, (*[5]any){render.point{X:1}, (*render.point){X:2}, 3, <REC(*[5]any)>, any(nil)}, any(nil)}`)
+}
+
 func TestRenderRecursiveMap(t *testing.T) {
 	m := map[string]any{}
 	foo := "foo"
@@ -243,7 +282,7 @@ func TestMapSortRendering(t *testing.T) {
 		},
 		{
 			map[uintptr]struct{}{1: {}, 2: {}, 3: {}, 4: {}, 5: {}, 6: {}, 7: {}, 8: {}},
-			"map[uintptr]struct {}{1:{}, 2:{}, 3:{}, 4:{}, 5:{}, 6:{}, 7:{}, 8:{}}",
+			"map[uintptr]struct {}{0x1:{}, 0x2:{}, 0x3:{}, 0x4:{}, 0x5:{}, 0x6:{}, 0x7:{}, 0x8:{}}",
 		},
 		{
 			namedMapType{10: struct{ a int }{20}},
@@ -271,7 +310,7 @@ func TestMapSortRendering(t *testing.T) {
 		},
 		{
 			map[chan int]string{nil: "a", chans[0]: "b", chans[1]: "c", chans[2]: "d", chans[3]: "e", chans[4]: "f"},
-			`map[(chan int)]string{(chan int)(PTR):"a", (chan int)(PTR):"b", (chan int)(PTR):"c", (chan int)(PTR):"d", (chan int)(PTR):"e", (chan int)(PTR):"f"}`,
+			`map[(chan int)]string{(chan int)(nil):"a", (chan int)(PTR):"b", (chan int)(PTR):"c", (chan int)(PTR):"d", (chan int)(PTR):"e", (chan int)(PTR):"f"}`,
 		},
 	}
 
@@ -279,3 +318,2715 @@ func TestMapSortRendering(t *testing.T) {
 		assertRendersLike(t, reflect.TypeOf(tc.in).Name(), tc.in, tc.expect)
 	}
 }
+
+func TestRenderStructKeysSortByField(t *testing.T) {
+	type key struct {
+		N int
+		S string
+	}
+	m := map[key]int{{10, "a"}: 1, {9, "z"}: 2, {9, "b"}: 3, {-1, "c"}: 4}
+
+	// Sorting by rendered keys would put 10 before 9; fields are compared by
+	// value instead, in declaration order.
+	assertRendersLike(t, "Struct keys", m,
+		`map[render.key]int{render.key{N:-1, S:"c"}:4, render.key{N:9, S:"b"}:3, render.key{N:9, S:"z"}:2, render.key{N:10, S:"a"}:1}`)
+}
+
+func TestRenderMapOfSlicesKeepsOrder(t *testing.T) {
+	m := map[string][]int{"b": {3, 1, 2}, "a": {9, 8, 7}, "c": nil}
+
+	assertRendersLike(t, "Map of slices", m,
+		`map[string][]int{"a":{9, 8, 7}, "b":{3, 1, 2}, "c":nil}`)
+	assertRendersWithLike(t, "Compact", m, RenderOptions{OmitTypePrefix: true},
+		`{"a":{9, 8, 7}, "b":{3, 1, 2}, "c":nil}`)
+	assertRendersLike(t, "Nested maps", map[int]map[string][]string{2: {"y": {"z", "a"}}, 1: {"x": {"b", "a"}}},
+		`map[int]map[string][]string{1:{"x":{"b", "a"}}, 2:{"y":{"z", "a"}}}`)
+}
+
+func TestSortedMapKeys(t *testing.T) {
+	type mapKey struct{ a, b int }
+
+	chans := make(chanList, 5)
+	for i := range chans {
+		chans[i] = make(chan int)
+	}
+	sort.Sort(chans)
+
+	ptrs := []*int{new(int), new(int), new(int)}
+	sort.Slice(ptrs, func(i, j int) bool {
+		return uintptr(unsafe.Pointer(ptrs[i])) < uintptr(unsafe.Pointer(ptrs[j]))
+	})
+
+	tcs := []struct {
+		in     any
+		expect []any
+	}{
+		{
+			map[uint32]struct{}{3: {}, 1: {}, 2: {}},
+			[]any{uint32(1), uint32(2), uint32(3)},
+		},
+		{
+			map[int8]struct{}{3: {}, 1: {}, 2: {}},
+			[]any{int8(1), int8(2), int8(3)},
+		},
+		{
+			map[uintptr]struct{}{3: {}, 1: {}, 2: {}},
+			[]any{uintptr(1), uintptr(2), uintptr(3)},
+		},
+		{
+			map[mapKey]struct{}{mapKey{3, 1}: {}, mapKey{1, 3}: {}, mapKey{1, 2}: {}, mapKey{2, 1}: {}},
+			[]any{mapKey{1, 2}, mapKey{1, 3}, mapKey{2, 1}, mapKey{3, 1}},
+		},
+		{
+			map[float64]struct{}{10.5: {}, 10.15: {}, 1203: {}, 1: {}, 2: {}},
+			[]any{1.0, 2.0, 10.15, 10.5, 1203.0},
+		},
+		{
+			map[bool]struct{}{true: {}, false: {}},
+			[]any{false, true},
+		},
+		{
+			map[any]struct{}{"foo": {}, 3: {}, 1: {}, 2: {}},
+			[]any{1, 2, 3, "foo"},
+		},
+		{
+			map[complex64]struct{}{1 + 2i: {}, 2 + 1i: {}, 3 + 1i: {}, 1 + 3i: {}},
+			[]any{complex64(1 + 2i), complex64(1 + 3i), complex64(2 + 1i), complex64(3 + 1i)},
+		},
+		{
+			map[chan int]string{nil: "a", chans[3]: "b", chans[1]: "c", chans[4]: "d", chans[0]: "e", chans[2]: "f"},
+			[]any{(chan int)(nil), chans[0], chans[1], chans[2], chans[3], chans[4]},
+		},
+		{
+			map[any]struct{}{2: {}, ptrs[2]: {}, chans[1]: {}, 1: {}, ptrs[0]: {}, chans[0]: {}, ptrs[1]: {}},
+			[]any{ptrs[0], ptrs[1], ptrs[2], chans[0], chans[1], 1, 2},
+		},
+	}
+
+	for _, tc := range tcs {
+		keys := SortedMapKeys(reflect.ValueOf(tc.in))
+		act := make([]any, len(keys))
+		for i, k := range keys {
+			act[i] = k.Interface()
+		}
+		if !reflect.DeepEqual(act, tc.expect) {
+			t.Errorf("[%s] keys did not sort as expected:\nExpected: %v\nActual  : %v", reflect.TypeOf(tc.in), tc.expect, act)
+		}
+	}
+
+	if keys := SortedMapKeys(reflect.Value{}); keys != nil {
+		t.Errorf("keys of the zero Value: %v", keys)
+	}
+}
+
+func TestRenderOmitZero(t *testing.T) {
+	type testStruct struct {
+		Name string
+		I    any
+		When time.Time
+
+		m string
+	}
+
+	opts := RenderOptions{OmitZero: true}
+	assertRendersWithLike(t, "Mixed struct", testStruct{Name: "foo"}, opts,
+		`render.testStruct{Name:"foo"}`)
+	assertRendersWithLike(t, "Mixed struct with time", testStruct{I: 1, When: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}, opts,
+		`render.testStruct{I:1, When:time.Time{2000-01-01 00:00:00 +0000 UTC}}`)
+	assertRendersWithLike(t, "All-zero struct", testStruct{}, opts,
+		`render.testStruct{}`)
+	assertRendersLike(t, "All-zero struct without option", testStruct{},
+		`render.testStruct{Name:"", I:any(nil), When:time.Time{0}, m:""}`)
+}
+
+func TestRenderChanDirection(t *testing.T) {
+	bidi := make(chan int, 4)
+	var send chan<- int = make(chan int, 2)
+	var recv <-chan int = make(chan int)
+
+	assertRendersLike(t, "Bidirectional", bidi, `(chan int)(PTR)`)
+	assertRendersLike(t, "Send-only", send, `(chan<- int)(PTR)`)
+	assertRendersLike(t, "Receive-only", recv, `(<-chan int)(PTR)`)
+
+	opts := RenderOptions{ChanCapacity: true}
+	assertRendersWithLike(t, "Bidirectional with capacity", bidi, opts, `(chan int, cap=4)(PTR)`)
+	assertRendersWithLike(t, "Send-only with capacity", send, opts, `(chan<- int, cap=2)(PTR)`)
+	assertRendersWithLike(t, "Receive-only with capacity", recv, opts, `(<-chan int, cap=0)(PTR)`)
+	assertRendersWithLike(t, "Pointer with capacity", &bidi, opts, `(*chan int, cap=4)(PTR)`)
+}
+
+func TestRenderNestedInterfaceMaps(t *testing.T) {
+	v := map[string]any{
+		"ints": map[int]string{30: "c", 10: "a", 20: "b", -5: "z"},
+		"mixed": map[any]any{
+			"x": 1,
+			2:   map[float64]bool{2.5: true, 1.5: false},
+			1:   []any{map[uint8]int{9: 9, 3: 3}},
+		},
+	}
+
+	exp := `map[string]any{` +
+		`"ints":map[int]string{-5:"z", 10:"a", 20:"b", 30:"c"}, ` +
+		`"mixed":map[any]any{1:[]any{map[uint8]int{3:3, 9:9}}, 2:map[float64]bool{1.5:false, 2.5:true}, "x":1}}`
+	for i := 0; i < 20; i++ {
+		assertRendersLike(t, fmt.Sprintf("Nested interface maps, pass #%d", i), v, exp)
+	}
+}
+
+func TestRenderBytesAsString(t *testing.T) {
+	type rawBytes []byte
+	type testStruct struct{ Payload []byte }
+
+	opts := RenderOptions{BytesAsString: true}
+	for _, tc := range []struct {
+		name string
+		in   any
+		s    string
+	}{
+		{"Text", []byte("hello"), `[]byte("hello")`},
+		{"Text with whitespace", []byte("a\tb\n"), `[]byte("a\tb\n")`},
+		{"Binary", []byte{0x00, 0xff, 0x10}, `[]byte(0x00ff10)`},
+		{"Invalid UTF-8", []byte{'a', 0xc3}, `[]byte(0x61c3)`},
+		{"Nil", []byte(nil), `[]uint8(nil)`},
+		{"Empty", []byte{}, `[]uint8{}`},
+		{"Named", rawBytes("hi"), `render.rawBytes("hi")`},
+		{"Pointer", &[]byte{'h', 'i'}, `(*[]byte)("hi")`},
+		{"Field", testStruct{[]byte("hi")}, `render.testStruct{Payload:[]byte("hi")}`},
+		{"Nested", [][]byte{[]byte("hi"), {0x01}}, `[][]uint8{"hi", 0x01}`},
+	} {
+		assertRendersWithLike(t, tc.name, tc.in, opts, tc.s)
+	}
+
+	assertRendersLike(t, "Default", []byte("hi"), `[]uint8{104, 105}`)
+}
+
+func TestRenderOmitTypePrefix(t *testing.T) {
+	type testStruct struct {
+		Name string
+		I    any
+
+		m string
+	}
+	type myIntType int
+	type myStringMap map[string]string
+
+	opts := RenderOptions{OmitTypePrefix: true}
+	for _, tc := range []struct {
+		name    string
+		in      any
+		full    string
+		compact string
+	}{
+		{"Named int", myIntType(12), `render.myIntType(12)`, `12`},
+		{"Named map", myStringMap{"foo": "bar"}, `render.myStringMap{"foo":"bar"}`, `{"foo":"bar"}`},
+		{"Pointer to struct", &testStruct{Name: "foo"},
+			`(*render.testStruct){Name:"foo", I:any(nil), m:""}`, `{Name:"foo", I:nil, m:""}`},
+		{"Nil pointer", (*testStruct)(nil), `(*render.testStruct)(nil)`, `nil`},
+		{"Nil map", myStringMap(nil), `render.myStringMap(nil)`, `nil`},
+		{"Slice of pointers", []*myIntType{nil}, `[]*render.myIntType{(*render.myIntType)(nil)}`, `{nil}`},
+		{"Channel", make(chan int), `(chan int)(PTR)`, `PTR`},
+		{"Uintptr", uintptr(16), `uintptr(0x10)`, `0x10`},
+	} {
+		assertRendersLike(t, tc.name, tc.in, tc.full)
+		assertRendersWithLike(t, tc.name+" (compact)", tc.in, opts, tc.compact)
+	}
+
+	s := &testStruct{Name: "recursive"}
+	s.I = s
+	assertRendersWithLike(t, "Recursive struct (compact)", s, opts, `{Name:"recursive", I:<REC>, m:""}`)
+}
+
+type panickyStringer struct{ v int }
+
+func (p panickyStringer) String() string { panic("boom") }
+
+type plainStringer int
+
+func (p plainStringer) String() string { return fmt.Sprintf("plain#%d", int(p)) }
+
+func TestRenderPanicSafety(t *testing.T) {
+	type testStruct struct {
+		Before string
+		Bad    panickyStringer
+		After  plainStringer
+		List   []any
+	}
+
+	v := testStruct{
+		Before: "a",
+		Bad:    panickyStringer{1},
+		After:  2,
+		List:   []any{panickyStringer{2}, plainStringer(3)},
+	}
+
+	assertRendersWithLike(t, "Panicking stringer", v, RenderOptions{Stringers: true},
+		`render.testStruct{Before:"a", Bad:<PANIC: boom>, After:render.plainStringer("plain#2"), `+
+			`List:[]any{<PANIC: boom>, render.plainStringer("plain#3")}}`)
+	assertRendersLike(t, "Stringers disabled", v,
+		`render.testStruct{Before:"a", Bad:render.panickyStringer{v:1}, After:render.plainStringer(2), `+
+			`List:[]any{render.panickyStringer{v:2}, render.plainStringer(3)}}`)
+}
+
+type stringerError struct{}
+
+func (stringerError) Error() string  { return "error text" }
+func (stringerError) String() string { return "string text" }
+
+func TestRenderMethods(t *testing.T) {
+	err := errors.New("failed")
+
+	assertRendersWithLike(t, "Error", err, RenderOptions{Errors: true}, `(*errors.errorString)("failed")`)
+	assertRendersWithLike(t, "Error without option", err, RenderOptions{Stringers: true}, `(*errors.errorString){s:"failed"}`)
+	assertRendersWithLike(t, "Error and Stringer", stringerError{}, RenderOptions{Errors: true, Stringers: true},
+		`render.stringerError("error text")`)
+	assertRendersWithLike(t, "Stringer only", stringerError{}, RenderOptions{Stringers: true},
+		`render.stringerError("string text")`)
+}
+
+func TestRenderRuns(t *testing.T) {
+	opts := RenderOptions{MinRunLength: 8}
+
+	assertRendersWithLike(t, "Identical array", [1000]int{}, opts, `[1000]int{0 x1000}`)
+	assertRendersWithLike(t, "Partial run", []int{1, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 3, 3}, opts,
+		`[]int{1, 2, 0 x9, 3, 3}`)
+	assertRendersWithLike(t, "Short runs", []int{1, 1, 1, 2, 1, 1}, opts, `[]int{1, 1, 1, 2, 1, 1}`)
+	assertRendersWithLike(t, "No runs", []string{"a", "b", "c"}, opts, `[]string{"a", "b", "c"}`)
+	assertRendersWithLike(t, "Empty", []int{}, opts, `[]int{}`)
+	assertRendersWithLike(t, "Threshold of one", []int{1, 2, 2}, RenderOptions{MinRunLength: 1},
+		`[]int{1 x1, 2 x2}`)
+}
+
+func TestRenderTypeHandlers(t *testing.T) {
+	type myIntType int
+	type testStruct struct {
+		ID    myIntType
+		Other plainStringer
+	}
+
+	opts := RenderOptions{
+		Stringers: true,
+		TypeHandlers: map[reflect.Type]func(any) string{
+			reflect.TypeOf(myIntType(0)): func(v any) string {
+				return fmt.Sprintf("#%d", v.(myIntType))
+			},
+			reflect.TypeOf(plainStringer(0)): func(v any) string {
+				return "handled"
+			},
+		},
+	}
+
+	assertRendersWithLike(t, "Standalone", myIntType(7), opts, `render.myIntType(#7)`)
+	assertRendersWithLike(t, "Slice", []myIntType{1, 2}, opts, `[]render.myIntType{render.myIntType(#1), render.myIntType(#2)}`)
+	assertRendersWithLike(t, "Map", map[string]myIntType{"a": 1}, opts, `map[string]render.myIntType{"a":render.myIntType(#1)}`)
+	assertRendersWithLike(t, "Map key", map[myIntType]string{3: "c", 1: "a"}, opts, `map[render.myIntType]string{#1:"a", #3:"c"}`)
+	assertRendersWithLike(t, "Struct", testStruct{ID: 4, Other: 5}, opts,
+		`render.testStruct{ID:render.myIntType(#4), Other:render.plainStringer(handled)}`)
+	assertRendersWithLike(t, "Compact", []any{myIntType(1)}, RenderOptions{OmitTypePrefix: true, TypeHandlers: opts.TypeHandlers},
+		`{#1}`)
+}
+
+func TestRenderMarkTypedNils(t *testing.T) {
+	type testStruct struct {
+		I any
+		E error
+	}
+
+	opts := RenderOptions{MarkTypedNils: true}
+	var typedNil *testStruct
+	var nilErr *stringerError
+
+	assertRendersWithLike(t, "Bare nil", []any{nil}, opts, `[]any{any(nil)}`)
+	assertRendersWithLike(t, "Boxed typed nil", []any{typedNil}, opts,
+		`[]any{any((*render.testStruct)(nil) <TYPED-NIL>)}`)
+	assertRendersWithLike(t, "Boxed nil map", []any{map[string]int(nil)}, opts,
+		`[]any{any(map[string]int(nil) <TYPED-NIL>)}`)
+	assertRendersWithLike(t, "Fields", testStruct{I: typedNil, E: nilErr}, opts,
+		`render.testStruct{I:any((*render.testStruct)(nil) <TYPED-NIL>), E:error((*render.stringerError)(nil) <TYPED-NIL>)}`)
+	assertRendersWithLike(t, "Nil fields", testStruct{}, opts,
+		`render.testStruct{I:any(nil), E:error(nil)}`)
+	assertRendersLike(t, "Boxed typed nil without option", []any{typedNil}, `[]any{(*render.testStruct)(nil)}`)
+}
+
+func TestRenderShowTags(t *testing.T) {
+	type testStruct struct {
+		Name  string `json:"name"`
+		Count int    `json:"count,omitempty" db:"cnt"`
+		Plain bool
+	}
+
+	v := testStruct{Name: "foo", Count: 2}
+	assertRendersWithLike(t, "Tags", v, RenderOptions{ShowTags: true},
+		`render.testStruct{Name(json:"name"):"foo", Count(json:"count,omitempty" db:"cnt"):2, Plain:false}`)
+	assertRendersLike(t, "No tags", v, `render.testStruct{Name:"foo", Count:2, Plain:false}`)
+}
+
+func TestRenderNonFiniteFloatKeys(t *testing.T) {
+	m := map[float64]string{
+		math.NaN():   "nan",
+		math.Inf(1):  "+inf",
+		2.5:          "b",
+		math.Inf(-1): "-inf",
+		-1:           "a",
+	}
+
+	exp := `map[float64]string{-Inf:"-inf", -1:"a", 2.5:"b", +Inf:"+inf", NaN:"nan"}`
+	for i := 0; i < 10; i++ {
+		assertRendersLike(t, fmt.Sprintf("Non-finite keys, pass #%d", i), m, exp)
+	}
+
+	keys := SortedMapKeys(reflect.ValueOf(map[float32]struct{}{
+		float32(math.NaN()): {}, float32(math.Inf(1)): {}, 0: {}, float32(math.Inf(-1)): {},
+	}))
+	if act := Render(keys[0].Interface()) + " " + Render(keys[1].Interface()) + " " +
+		Render(keys[2].Interface()) + " " + Render(keys[3].Interface()); act != "-Inf 0 +Inf NaN" {
+		t.Errorf("float32 keys did not sort as expected: %s", act)
+	}
+}
+
+func TestRenderTimeLayout(t *testing.T) {
+	type testStruct struct{ When time.Time }
+
+	now := time.Now()
+	opts := RenderOptions{TimeLayout: time.RFC3339Nano}
+	if a, b := RenderWith(now, opts), RenderWith(now.Round(0), opts); a != b {
+		t.Errorf("monotonic clock reading affected rendering: %s != %s", a, b)
+	}
+
+	date := time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC)
+	assertRendersWithLike(t, "Layout", testStruct{date}, opts, `render.testStruct{When:time.Time{2000-01-02T03:04:05.000000006Z}}`)
+	assertRendersWithLike(t, "Zero", testStruct{}, opts, `render.testStruct{When:time.Time{0}}`)
+}
+
+func TestRenderFlattenEmbedded(t *testing.T) {
+	type Inner struct {
+		ID   int
+		Name string
+	}
+	type Other struct{ Name string }
+	type outer struct {
+		Inner
+		Other
+		Extra bool
+	}
+
+	v := outer{Inner{1, "inner"}, Other{"other"}, true}
+	assertRendersLike(t, "Nested", v,
+		`render.outer{Inner:render.Inner{ID:1, Name:"inner"}, Other:render.Other{Name:"other"}, Extra:true}`)
+	assertRendersWithLike(t, "Flattened", v, RenderOptions{FlattenEmbedded: true},
+		`render.outer{ID:1, Inner.Name:"inner", Other.Name:"other", Extra:true}`)
+
+	type single struct {
+		Inner
+		Name string
+	}
+	assertRendersWithLike(t, "Shadowed", single{Inner{2, "promoted"}, "direct"}, RenderOptions{FlattenEmbedded: true},
+		`render.single{ID:2, Inner.Name:"promoted", Name:"direct"}`)
+}
+
+func TestRenderMaxTotalBytes(t *testing.T) {
+	const budget = 100
+	const marker = "...<truncated>"
+
+	m := make(map[string]int, 1000)
+	for i := 0; i < 1000; i++ {
+		m[fmt.Sprintf("key%04d", i)] = i
+	}
+
+	act := RenderWith(m, RenderOptions{MaxTotalBytes: budget})
+	if !strings.HasSuffix(act, marker) {
+		t.Errorf("truncated output is missing its marker: %s", act)
+	}
+	if len(act) > budget+len(marker) {
+		t.Errorf("truncated output is %d bytes, exceeding the %d byte budget: %s", len(act), budget, act)
+	}
+	if exp := Render(m)[:budget] + marker; act != exp {
+		t.Errorf("truncated output is not a prefix of the full output:\nExpected: %s\nActual  : %s", exp, act)
+	}
+
+	assertRendersWithLike(t, "Within budget", []int{1, 2, 3}, RenderOptions{MaxTotalBytes: budget}, `[]int{1, 2, 3}`)
+	assertRendersWithLike(t, "Multi-byte runes", "ééééé", RenderOptions{MaxTotalBytes: 4}, `"é...<truncated>`)
+}
+
+func TestRenderUnsafePointer(t *testing.T) {
+	type testStruct struct {
+		P unsafe.Pointer
+	}
+
+	i, j := 1, 2
+	p := unsafe.Pointer(&i)
+	assertRendersLike(t, "Standalone", p, `unsafe.Pointer(PTR)`)
+	assertRendersLike(t, "Field", testStruct{p}, `render.testStruct{P:unsafe.Pointer(PTR)}`)
+	assertRendersLike(t, "Pointer to", &p, `(*unsafe.Pointer)(PTR)`)
+	assertRendersLike(t, "Map keys", map[unsafe.Pointer]struct{}{p: {}, unsafe.Pointer(&j): {}},
+		`map[unsafe.Pointer]struct {}{unsafe.Pointer(PTR):{}, unsafe.Pointer(PTR):{}}`)
+}
+
+func TestRenderQualifyMapKeyTypes(t *testing.T) {
+	type myStringType string
+	type myIntType int
+
+	opts := RenderOptions{QualifyMapKeyTypes: true}
+	for _, tc := range []struct {
+		name      string
+		in        any
+		plain     string
+		qualified string
+	}{
+		{"Named string keys", map[myStringType]int{"k": 1, "a": 2},
+			`map[render.myStringType]int{"a":2, "k":1}`,
+			`map[render.myStringType]int{render.myStringType("a"):2, render.myStringType("k"):1}`},
+		{"Named int keys", map[myIntType]string{2: "b", 1: "a"},
+			`map[render.myIntType]string{1:"a", 2:"b"}`,
+			`map[render.myIntType]string{render.myIntType(1):"a", render.myIntType(2):"b"}`},
+		{"Builtin keys", map[string]int{"k": 1},
+			`map[string]int{"k":1}`,
+			`map[string]int{"k":1}`},
+	} {
+		assertRendersLike(t, tc.name, tc.in, tc.plain)
+		assertRendersWithLike(t, tc.name+" (qualified)", tc.in, opts, tc.qualified)
+	}
+}
+
+type byteReader struct{ data []byte }
+
+func (b *byteReader) Read(p []byte) (int, error) { return copy(p, b.data), io.EOF }
+
+func TestRenderShowInterfaceTypes(t *testing.T) {
+	type testStruct struct {
+		R io.Reader
+		I any
+	}
+
+	v := testStruct{R: &byteReader{[]byte{1}}, I: 5}
+	assertRendersLike(t, "Default", v,
+		`render.testStruct{R:(*render.byteReader){data:[]uint8{1}}, I:5}`)
+	assertRendersWithLike(t, "Interface types", v, RenderOptions{ShowInterfaceTypes: true},
+		`render.testStruct{R:io.Reader((*render.byteReader){data:[]uint8{1}}), I:any(5)}`)
+	assertRendersWithLike(t, "Nil interface", testStruct{}, RenderOptions{ShowInterfaceTypes: true},
+		`render.testStruct{R:io.Reader(nil), I:any(nil)}`)
+}
+
+func TestRenderIndent(t *testing.T) {
+	type testStruct struct {
+		Name string
+		M    map[string]int
+		L    []int
+		E    []int
+	}
+
+	v := testStruct{Name: "foo", M: map[string]int{"b": 2, "a": 1}, L: []int{1}, E: []int{}}
+	assertRendersWithLike(t, "Indented", v, RenderOptions{Indent: "  "},
+		"render.testStruct{\n"+
+			"  Name:\"foo\",\n"+
+			"  M:map[string]int{\n"+
+			"    \"a\":1,\n"+
+			"    \"b\":2,\n"+
+			"  },\n"+
+			"  L:[]int{\n"+
+			"    1,\n"+
+			"  },\n"+
+			"  E:[]int{},\n"+
+			"}")
+	assertRendersWithLike(t, "Indented runs", []int{0, 0, 0, 1}, RenderOptions{Indent: "\t", MinRunLength: 2},
+		"[]int{\n\t0 x3,\n\t1,\n}")
+}
+
+func TestRenderByteAlias(t *testing.T) {
+	type testStruct struct {
+		B []byte
+	}
+
+	opts := RenderOptions{ByteAlias: true}
+	assertRendersWithLike(t, "Nil", []byte(nil), opts, `[]byte(nil)`)
+	assertRendersWithLike(t, "Empty", []byte{}, opts, `[]byte{}`)
+	assertRendersWithLike(t, "Populated", []byte{1, 2}, opts, `[]byte{1, 2}`)
+	assertRendersWithLike(t, "Array", [4]byte{1, 2, 3, 4}, opts, `[4]byte{1, 2, 3, 4}`)
+	assertRendersWithLike(t, "Map", map[byte][]byte{1: nil}, opts, `map[byte][]byte{1:nil}`)
+	assertRendersWithLike(t, "Pointer", &[]byte{1}, opts, `(*[]byte){1}`)
+	assertRendersWithLike(t, "Field", testStruct{[]byte{1}}, opts, `render.testStruct{B:[]byte{1}}`)
+	assertRendersLike(t, "Without option", [4]byte{1, 2, 3, 4}, `[4]uint8{1, 2, 3, 4}`)
+}
+
+func TestRenderLines(t *testing.T) {
+	type inner struct {
+		Tags []string
+	}
+	type testStruct struct {
+		Name  string
+		Inner inner
+		Attrs map[string]int
+	}
+
+	act := RenderLines(testStruct{
+		Name:  "foo\nbar",
+		Inner: inner{Tags: []string{"a", "b"}},
+		Attrs: map[string]int{"y": 2, "x": 1},
+	})
+	exp := []string{
+		`render.testStruct{`,
+		`	Name:"foo\nbar",`,
+		`	Inner:render.inner{`,
+		`		Tags:[]string{`,
+		`			"a",`,
+		`			"b",`,
+		`		},`,
+		`	},`,
+		`	Attrs:map[string]int{`,
+		`		"x":1,`,
+		`		"y":2,`,
+		`	},`,
+		`}`,
+	}
+	if !reflect.DeepEqual(act, exp) {
+		t.Errorf("lines did not match expectations:\nExpected: %q\nActual  : %q", exp, act)
+	}
+
+	if act := RenderLines(42); !reflect.DeepEqual(act, []string{"42"}) {
+		t.Errorf("scalar lines did not match expectations: %q", act)
+	}
+}
+
+func TestRenderReflectValue(t *testing.T) {
+	type testStruct struct {
+		V reflect.Value
+	}
+
+	assertRendersLike(t, "Nil", nil, `nil`)
+	assertRendersLike(t, "Invalid", reflect.Value{}, `<invalid>`)
+	assertRendersLike(t, "Invalid field", testStruct{}, `render.testStruct{V:<invalid>}`)
+	assertRendersLike(t, "Valid", reflect.ValueOf(5), `reflect.Value(5)`)
+	assertRendersLike(t, "Valid field", testStruct{reflect.ValueOf("x")}, `render.testStruct{V:reflect.Value("x")}`)
+	assertRendersLike(t, "Slice", []reflect.Value{{}, reflect.ValueOf(1)}, `[]reflect.Value{<invalid>, reflect.Value(1)}`)
+	assertRendersWithLike(t, "Compact", reflect.ValueOf(5), RenderOptions{OmitTypePrefix: true}, `5`)
+}
+
+func TestRenderSeparators(t *testing.T) {
+	type testStruct struct {
+		Name string
+		L    []int
+	}
+
+	opts := RenderOptions{MapKVSep: " => ", ItemSep: "; "}
+	assertRendersWithLike(t, "Map", map[string]int{"b": 2, "a": 1}, opts, `map[string]int{"a" => 1; "b" => 2}`)
+	assertRendersWithLike(t, "Struct", testStruct{"foo", []int{1, 2}}, opts, `render.testStruct{Name => "foo"; L => []int{1; 2}}`)
+
+	opts.Indent = "\t"
+	assertRendersWithLike(t, "Indented", []int{1, 2}, opts, "[]int{\n\t1;\n\t2;\n}")
+}
+
+func TestRenderNilAndEmptyMaps(t *testing.T) {
+	type myStringMap map[string]string
+	type myIntMap map[int]string
+	type mapKey struct{ a, b int }
+	type myStructMap map[mapKey]string
+
+	for _, tc := range []struct {
+		name string
+		in   any
+		s    string
+	}{
+		{"Nil string-keyed", map[string]string(nil), `map[string]string(nil)`},
+		{"Empty string-keyed", map[string]string{}, `map[string]string{}`},
+		{"Nil int-keyed", map[int]string(nil), `map[int]string(nil)`},
+		{"Empty int-keyed", map[int]string{}, `map[int]string{}`},
+		{"Nil struct-keyed", map[mapKey]string(nil), `map[render.mapKey]string(nil)`},
+		{"Empty struct-keyed", map[mapKey]string{}, `map[render.mapKey]string{}`},
+		{"Nil named string-keyed", myStringMap(nil), `render.myStringMap(nil)`},
+		{"Empty named string-keyed", myStringMap{}, `render.myStringMap{}`},
+		{"Nil named int-keyed", myIntMap(nil), `render.myIntMap(nil)`},
+		{"Empty named int-keyed", myIntMap{}, `render.myIntMap{}`},
+		{"Nil named struct-keyed", myStructMap(nil), `render.myStructMap(nil)`},
+		{"Empty named struct-keyed", myStructMap{}, `render.myStructMap{}`},
+		{"Nil and empty elements", []map[int]string{nil, {}}, `[]map[int]string{nil, {}}`},
+		{"Nil and empty named elements", []myIntMap{nil, {}}, `[]render.myIntMap{render.myIntMap(nil), render.myIntMap{}}`},
+		{"Nil and empty values", map[string]map[string]string{"a": nil, "b": {}}, `map[string]map[string]string{"a":nil, "b":{}}`},
+	} {
+		assertRendersLike(t, tc.name, tc.in, tc.s)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestRenderToAndSize(t *testing.T) {
+	type testStruct struct {
+		Name string
+		M    map[string][]int
+	}
+
+	big := make([]int, 500)
+	for _, tc := range []struct {
+		in   any
+		opts RenderOptions
+	}{
+		{nil, RenderOptions{}},
+		{"héllo", RenderOptions{}},
+		{testStruct{"foo", map[string][]int{"a": {1, 2}}}, RenderOptions{}},
+		{testStruct{"foo", map[string][]int{"a": {1, 2}}}, RenderOptions{Indent: "  "}},
+		{big, RenderOptions{MaxTotalBytes: 50}},
+		{big, RenderOptions{MinRunLength: 2}},
+	} {
+		exp := RenderWith(tc.in, tc.opts)
+		if act := RenderSize(tc.in, tc.opts); act != len(exp) {
+			t.Errorf("RenderSize(%s) = %d, expected %d", exp, act, len(exp))
+		}
+
+		var buf bytes.Buffer
+		if err := RenderTo(&buf, tc.in, tc.opts); err != nil {
+			t.Errorf("RenderTo(%s) failed: %v", exp, err)
+		}
+		if act := buf.String(); act != exp {
+			t.Errorf("RenderTo did not match RenderWith:\nExpected: %s\nActual  : %s", exp, act)
+		}
+	}
+
+	if err := RenderTo(failingWriter{}, 1, RenderOptions{}); err == nil || err.Error() != "write failed" {
+		t.Errorf("expected the writer's error, got: %v", err)
+	}
+	if act := RenderSize(1, RenderOptions{}); act != len(Render(1)) {
+		t.Errorf("RenderSize(1) = %d", act)
+	}
+}
+
+func TestRenderSignedMapKeys(t *testing.T) {
+	assertRendersLike(t, "int keys", map[int]struct{}{100: {}, 3: {}, -1: {}, 0: {}, -5: {}},
+		`map[int]struct {}{-5:{}, -1:{}, 0:{}, 3:{}, 100:{}}`)
+	assertRendersLike(t, "int8 keys", map[int8]struct{}{127: {}, -128: {}, 1: {}, -1: {}, 0: {}},
+		`map[int8]struct {}{-128:{}, -1:{}, 0:{}, 1:{}, 127:{}}`)
+	assertRendersLike(t, "int64 keys", map[int64]struct{}{math.MaxInt64: {}, math.MinInt64: {}, -2: {}},
+		`map[int64]struct {}{-9223372036854775808:{}, -2:{}, 9223372036854775807:{}}`)
+	assertRendersLike(t, "Interface keys", map[any]struct{}{10: {}, -10: {}, 0: {}},
+		`map[any]struct {}{-10:{}, 0:{}, 10:{}}`)
+}
+
+func TestRenderPointerToCollections(t *testing.T) {
+	s := []int{1, 2}
+	a := [3]int{1, 2, 3}
+	type testStruct struct {
+		S *[]int
+		A *[3]int
+	}
+
+	assertRendersLike(t, "Nil pointer to slice", (*[]int)(nil), `(*[]int)(nil)`)
+	assertRendersLike(t, "Pointer to slice", &s, `(*[]int){1, 2}`)
+	assertRendersLike(t, "Pointer to empty slice", &[]int{}, `(*[]int){}`)
+	assertRendersLike(t, "Nil pointer to array", (*[3]int)(nil), `(*[3]int)(nil)`)
+	assertRendersLike(t, "Pointer to array", &a, `(*[3]int){1, 2, 3}`)
+	assertRendersLike(t, "Fields", testStruct{&s, &a}, `render.testStruct{S:(*[]int){1, 2}, A:(*[3]int){1, 2, 3}}`)
+	assertRendersLike(t, "Nil fields", testStruct{}, `render.testStruct{S:(*[]int)(nil), A:(*[3]int)(nil)}`)
+}
+
+func TestRenderUnexportedTimes(t *testing.T) {
+	type myTypeWithTime struct{ Public, private time.Time }
+
+	date := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	opts := RenderOptions{UnexportedTimes: true}
+
+	assertRendersWithLike(t, "Populated", myTypeWithTime{date, date}, opts,
+		`render.myTypeWithTime{Public:time.Time{2000-01-01 00:00:00 +0000 UTC}, private:time.Time{2000-01-01 00:00:00 +0000 UTC}}`)
+	assertRendersWithLike(t, "Zero", myTypeWithTime{}, opts,
+		`render.myTypeWithTime{Public:time.Time{0}, private:time.Time{0}}`)
+	assertRendersWithLike(t, "Pointer", &myTypeWithTime{private: date}, opts,
+		`(*render.myTypeWithTime){Public:time.Time{0}, private:time.Time{2000-01-01 00:00:00 +0000 UTC}}`)
+	assertRendersWithLike(t, "Slice", []myTypeWithTime{{private: date}}, opts,
+		`[]render.myTypeWithTime{render.myTypeWithTime{Public:time.Time{0}, private:time.Time{2000-01-01 00:00:00 +0000 UTC}}}`)
+	assertRendersWithLike(t, "With layout", myTypeWithTime{private: date}, RenderOptions{UnexportedTimes: true, TimeLayout: "2006-01-02"},
+		`render.myTypeWithTime{Public:time.Time{0}, private:time.Time{2000-01-01}}`)
+}
+
+func TestRenderShowDynamicTypes(t *testing.T) {
+	type testStruct struct{ A int }
+
+	v := []any{1, "foo", testStruct{2}, nil, 3.5, &testStruct{3}}
+	assertRendersLike(t, "Default", v,
+		`[]any{1, "foo", render.testStruct{A:2}, any(nil), 3.5, (*render.testStruct){A:3}}`)
+	assertRendersWithLike(t, "Dynamic types", v, RenderOptions{ShowDynamicTypes: true},
+		`[]any{int(1), string("foo"), render.testStruct{A:2}, any(nil), float64(3.5), (*render.testStruct){A:3}}`)
+	assertRendersWithLike(t, "Map values", map[string]any{"a": uint8(1)}, RenderOptions{ShowDynamicTypes: true},
+		`map[string]any{"a":uint8(1)}`)
+	assertRendersWithLike(t, "Concrete elements", []int{1, 2}, RenderOptions{ShowDynamicTypes: true},
+		`[]int{1, 2}`)
+}
+
+type genericBox[T any] struct {
+	V T
+}
+
+type genericID int
+
+func TestRenderGenericTypes(t *testing.T) {
+	assertRendersLike(t, "Builtin type argument", genericBox[int]{1}, `render.genericBox[int]{V:1}`)
+	assertRendersLike(t, "Builtin type argument in slice", []genericBox[int]{{1}},
+		`[]render.genericBox[int]{render.genericBox[int]{V:1}}`)
+
+	// Named type arguments are qualified with their full package path.
+	named := reflect.TypeOf(genericBox[genericID]{}).String()
+	if !strings.HasSuffix(named, "/render.genericID]") {
+		t.Fatalf("unexpected type name: %s", named)
+	}
+	assertRendersLike(t, "Named type argument", genericBox[genericID]{2},
+		named+`{V:render.genericID(2)}`)
+	assertRendersLike(t, "Named type argument in slice", []genericBox[genericID]{{2}},
+		`[]`+named+`{`+named+`{V:render.genericID(2)}}`)
+	assertRendersLike(t, "Pointer", &genericBox[string]{"x"}, `(*render.genericBox[string]){V:"x"}`)
+}
+
+func TestRenderUnexportedStructKeys(t *testing.T) {
+	type key struct {
+		name string
+		ids  [2]int
+		tag  any
+	}
+
+	m := map[key]int{
+		{"b", [2]int{1, 2}, nil}: 1,
+		{"a", [2]int{3, 4}, 1}:   2,
+		{"a", [2]int{1, 2}, "x"}: 3,
+		{"a", [2]int{1, 2}, 2}:   4,
+	}
+	exp := `map[render.key]int{` +
+		`render.key{name:"a", ids:[2]int{1, 2}, tag:2}:4, ` +
+		`render.key{name:"a", ids:[2]int{1, 2}, tag:"x"}:3, ` +
+		`render.key{name:"a", ids:[2]int{3, 4}, tag:1}:2, ` +
+		`render.key{name:"b", ids:[2]int{1, 2}, tag:any(nil)}:1}`
+	for i := 0; i < 10; i++ {
+		assertRendersLike(t, "Unexported fields", m, exp)
+	}
+	assertRendersLike(t, "Array keys", map[[2]string]int{{"b", "a"}: 1, {"a", "b"}: 2},
+		`map[[2]string]int{[2]string{"a", "b"}:2, [2]string{"b", "a"}:1}`)
+}
+
+func TestRenderEqual(t *testing.T) {
+	type testStruct struct {
+		A int
+		B []string
+	}
+
+	for _, tc := range []struct {
+		name string
+		a, b any
+		exp  bool
+	}{
+		{"nil", nil, nil, true},
+		{"ints", 1, 1, true},
+		{"different ints", 1, 2, false},
+		{"different types", []int32{1}, []int64{1}, false},
+		{"structs", testStruct{1, []string{"a"}}, testStruct{1, []string{"a"}}, true},
+		{"different structs", testStruct{1, []string{"a"}}, testStruct{1, []string{"b"}}, false},
+		{"distinct pointers", &testStruct{A: 1}, &testStruct{A: 1}, true},
+		{"longer", []int{1}, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, false},
+		{"shorter", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, []int{1}, false},
+		{"maps", map[string]int{"a": 1, "b": 2}, map[string]int{"b": 2, "a": 1}, true},
+	} {
+		if act := RenderEqual(tc.a, tc.b); act != tc.exp {
+			t.Errorf("%s: RenderEqual(%s, %s) = %v, expected %v", tc.name, Render(tc.a), Render(tc.b), act, tc.exp)
+		}
+	}
+
+	// The monotonic clock reading is part of a time's default rendering.
+	now := time.Now()
+	if !RenderEqual(now, now) {
+		t.Error("expected identical times to render equally")
+	}
+	if RenderEqual(now, now.Round(0)) {
+		t.Error("expected the monotonic clock reading to affect rendering")
+	}
+	if !RenderEqual(now.Round(0), now.Round(0)) {
+		t.Error("expected stripped times to render equally")
+	}
+}
+
+func TestRenderMaxTotalBytesIndented(t *testing.T) {
+	type inner struct {
+		A int
+		B string
+	}
+	type outer struct {
+		Name  string
+		Inner inner
+		List  []inner
+	}
+
+	v := outer{"outer", inner{1, "one"}, []inner{{2, "two"}, {3, "three"}}}
+	assertRendersWithLike(t, "Nested", v, RenderOptions{Indent: "\t", MaxTotalBytes: 63},
+		"render.outer{\n"+
+			"\tName:\"outer\",\n"+
+			"\tInner:render.inner{\n"+
+			"\t\tA:1,\n"+
+			"\t\tB:\"o...<truncated>\n"+
+			"\t}\n"+
+			"}")
+
+	// Wherever the output is cut, every brace opened before the cut is closed.
+	full := RenderWith(v, RenderOptions{Indent: "\t"})
+	for budget := 1; budget < len(full); budget++ {
+		act := RenderWith(v, RenderOptions{Indent: "\t", MaxTotalBytes: budget})
+		if kept := act[:strings.Index(act, "...<truncated>")]; kept != full[:budget] {
+			t.Errorf("budget %d: output is not a prefix of the full output: %q", budget, act)
+		}
+		if open, closed := strings.Count(act, "{"), strings.Count(act, "}"); open != closed {
+			t.Errorf("budget %d: %d braces opened but %d closed: %q", budget, open, closed, act)
+		}
+	}
+
+	// Without indentation, output is simply cut.
+	assertRendersWithLike(t, "Compact", v, RenderOptions{MaxTotalBytes: 30},
+		`render.outer{Name:"outer", Inn...<truncated>`)
+}
+
+func TestRenderEnumNames(t *testing.T) {
+	type myIntType int
+	type myUintType uint8
+
+	opts := RenderOptions{EnumNames: map[reflect.Type]map[int64]string{
+		reflect.TypeOf(myIntType(0)):  {42: "FortyTwo", -1: "Invalid"},
+		reflect.TypeOf(myUintType(0)): {1: "One"},
+	}}
+
+	assertRendersWithLike(t, "Registered", myIntType(42), opts, `render.myIntType(FortyTwo)`)
+	assertRendersWithLike(t, "Negative", myIntType(-1), opts, `render.myIntType(Invalid)`)
+	assertRendersWithLike(t, "Unregistered value", myIntType(7), opts, `render.myIntType(7)`)
+	assertRendersWithLike(t, "Unsigned", []myUintType{1, 2}, opts,
+		`[]render.myUintType{render.myUintType(One), render.myUintType(2)}`)
+	assertRendersWithLike(t, "Slice", []myIntType{42, 7}, opts,
+		`[]render.myIntType{render.myIntType(FortyTwo), render.myIntType(7)}`)
+	assertRendersWithLike(t, "Pointer", &[]myIntType{42}[0], opts, `(*render.myIntType)(FortyTwo)`)
+	assertRendersWithLike(t, "Unregistered type", []int{42}, opts, `[]int{42}`)
+	assertRendersWithLike(t, "Without option", myIntType(42), RenderOptions{}, `render.myIntType(42)`)
+}
+
+type selfWrappingError struct{}
+
+func (e selfWrappingError) Error() string { return "self" }
+func (e selfWrappingError) Unwrap() error { return e }
+
+// multiError wraps several errors, like those returned by errors.Join.
+type multiError struct {
+	msg  string
+	errs []error
+}
+
+func (e multiError) Error() string   { return e.msg }
+func (e multiError) Unwrap() []error { return e.errs }
+
+func TestRenderErrorChains(t *testing.T) {
+	opts := RenderOptions{Errors: true}
+	root := errors.New("root")
+	inner := fmt.Errorf("inner: %w", root)
+
+	assertRendersWithLike(t, "Single wrap", inner, opts, `(*fmt.wrapError)("inner" <- "root")`)
+	assertRendersWithLike(t, "Triple wrap", fmt.Errorf("outer: %w", inner), opts,
+		`(*fmt.wrapError)("outer" <- "inner" <- "root")`)
+	assertRendersWithLike(t, "Message not ending with wrapped message", fmt.Errorf("%w (retrying)", root), opts,
+		`(*fmt.wrapError)("root (retrying)" <- "root")`)
+	other := errors.New("other")
+	assertRendersWithLike(t, "Joined", multiError{"inner: root\nother", []error{inner, other}}, opts,
+		`render.multiError(["inner" <- "root", "other"])`)
+	assertRendersWithLike(t, "Wrapping several", multiError{"both: root, other", []error{root, other}}, opts,
+		`render.multiError("both: root, other" <- ["root", "other"])`)
+	assertRendersWithLike(t, "Wrapping joined", fmt.Errorf("ctx: %w", multiError{"root\nroot", []error{root, nil, root}}), opts,
+		`(*fmt.wrapError)("ctx" <- ["root", "root"])`)
+	assertRendersWithLike(t, "In struct", struct{ Err error }{inner}, opts,
+		`struct { Err error }{Err:(*fmt.wrapError)("inner" <- "root")}`)
+	assertRendersWithLike(t, "Self wrapping", selfWrappingError{}, opts,
+		`render.selfWrappingError(`+strings.Repeat(`"self" <- `, maxErrorChain)+`"self")`)
+}
+
+func TestRenderShowIndices(t *testing.T) {
+	opts := RenderOptions{ShowIndices: true}
+
+	assertRendersWithLike(t, "String slice", []string{"foo", "bar"}, opts, `[]string{0:"foo", 1:"bar"}`)
+	assertRendersWithLike(t, "Int array", [3]int{4, 5, 6}, opts, `[3]int{0:4, 1:5, 2:6}`)
+	assertRendersWithLike(t, "Empty", []int{}, opts, `[]int{}`)
+	assertRendersWithLike(t, "Nested", [][]int{{1}, {2, 3}}, opts, `[][]int{0:{0:1}, 1:{0:2, 1:3}}`)
+	assertRendersWithLike(t, "Runs", []int{1, 0, 0, 0, 2}, RenderOptions{ShowIndices: true, MinRunLength: 3},
+		`[]int{0:1, 1:0 x3, 4:2}`)
+	assertRendersWithLike(t, "Indented", []string{"a", "b"}, RenderOptions{ShowIndices: true, Indent: "\t", MapKVSep: ": "},
+		"[]string{\n\t0: \"a\",\n\t1: \"b\",\n}")
+	assertRendersLike(t, "Default", []string{"foo", "bar"}, `[]string{"foo", "bar"}`)
+}
+
+func TestRenderFlagNames(t *testing.T) {
+	type Flags uint8
+	type Mode int
+
+	opts := RenderOptions{FlagNames: map[reflect.Type]map[int64]string{
+		reflect.TypeOf(Flags(0)): {1: "Read", 2: "Write", 4: "Exec", 0: "None"},
+		reflect.TypeOf(Mode(0)):  {1: "A", 6: "BC"},
+	}}
+
+	assertRendersWithLike(t, "Single", Flags(2), opts, `render.Flags(Write)`)
+	assertRendersWithLike(t, "Combined", Flags(3), opts, `render.Flags(Read|Write)`)
+	assertRendersWithLike(t, "Residual bits", Flags(0x15), opts, `render.Flags(Read|Exec|0x10)`)
+	assertRendersWithLike(t, "Named zero", Flags(0), opts, `render.Flags(None)`)
+	assertRendersWithLike(t, "Unnamed zero", Mode(0), opts, `render.Mode(0)`)
+	assertRendersWithLike(t, "Multi-bit flag", Mode(7), opts, `render.Mode(A|BC)`)
+	assertRendersWithLike(t, "Partial multi-bit flag", Mode(5), opts, `render.Mode(A|0x4)`)
+	assertRendersWithLike(t, "Slice", []Flags{1, 6}, opts, `[]render.Flags{render.Flags(Read), render.Flags(Write|Exec)}`)
+}
+
+func TestRenderNoExponent(t *testing.T) {
+	opts := RenderOptions{NoExponent: true}
+	a, b := 0.1, 0.2
+
+	assertRendersWithLike(t, "Large", 1e6, opts, `1000000`)
+	assertRendersWithLike(t, "Small", 1e-6, opts, `0.000001`)
+	assertRendersWithLike(t, "Normal", 3.25, opts, `3.25`)
+	assertRendersWithLike(t, "Precision", a+b, opts, `0.30000000000000004`)
+	assertRendersWithLike(t, "Infinity", math.Inf(-1), opts, `-Inf`)
+	assertRendersWithLike(t, "Map keys", map[float64]int{1203: 1, 1e6: 2}, opts, `map[float64]int{1203:1, 1000000:2}`)
+	assertRendersWithLike(t, "Float32", []float32{2e7}, opts, `[]float32{20000000}`)
+	assertRendersLike(t, "Without option", 1e6, `1e+06`)
+}
+
+func TestRenderRedact(t *testing.T) {
+	type credentials struct {
+		User, Token string
+	}
+	type config struct {
+		Name     string
+		Password string       `render:"redact"`
+		Creds    credentials  `json:"creds" render:"redact"`
+		CredsPtr *credentials `render:"redact"`
+	}
+
+	v := config{"prod", "hunter2", credentials{"u", "t"}, &credentials{"u", "t"}}
+	assertRendersWithLike(t, "Redacted", v, RenderOptions{Redact: true},
+		`render.config{Name:"prod", Password:string(<redacted>), Creds:render.credentials(<redacted>), CredsPtr:(*render.credentials)(<redacted>)}`)
+	assertRendersWithLike(t, "Compact", v, RenderOptions{Redact: true, OmitTypePrefix: true},
+		`{Name:"prod", Password:string(<redacted>), Creds:render.credentials(<redacted>), CredsPtr:(*render.credentials)(<redacted>)}`)
+	assertRendersLike(t, "Without option", config{Password: "hunter2"},
+		`render.config{Name:"", Password:"hunter2", Creds:render.credentials{User:"", Token:""}, CredsPtr:(*render.credentials)(nil)}`)
+}
+
+func TestRenderPointerToInterface(t *testing.T) {
+	type myType struct{ A int }
+
+	var nilStringer fmt.Stringer
+	var stringer fmt.Stringer = plainStringer(1)
+	var empty any = myType{2}
+	var number any = 3
+	pEmpty := &empty
+
+	assertRendersLike(t, "Nil interface", &nilStringer, `(*fmt.Stringer)(nil)`)
+	assertRendersLike(t, "Concrete value", &stringer, `(*fmt.Stringer)(render.plainStringer(1))`)
+	assertRendersLike(t, "Struct value", &empty, `(*any)(render.myType{A:2})`)
+	assertRendersLike(t, "Builtin value", &number, `(*any)(3)`)
+	assertRendersLike(t, "Double pointer", &pEmpty, `(**any)(render.myType{A:2})`)
+	assertRendersLike(t, "Field", struct{ S *fmt.Stringer }{&stringer},
+		`struct { S *fmt.Stringer }{(*fmt.Stringer)(render.plainStringer(1))}`)
+	assertRendersWithLike(t, "Dynamic types", &number, RenderOptions{ShowDynamicTypes: true}, `(*any)(int(3))`)
+	assertRendersWithLike(t, "Compact", &empty, RenderOptions{OmitTypePrefix: true}, `{A:2}`)
+}
+
+func TestRenderIncludeFields(t *testing.T) {
+	type user struct {
+		Name  string
+		Email string
+	}
+	type account struct {
+		ID      int
+		User    user
+		Friends []user
+		Owner   *user
+	}
+
+	v := account{1, user{"ann", "a@x"}, []user{{"bob", "b@x"}}, &user{"cat", "c@x"}}
+	assertRendersWithLike(t, "Top-level field", v, RenderOptions{IncludeFields: []string{"ID"}},
+		`render.account{ID:1, ...}`)
+	assertRendersWithLike(t, "Whole struct field", v, RenderOptions{IncludeFields: []string{"User"}},
+		`render.account{User:render.user{Name:"ann", Email:"a@x"}, ...}`)
+	assertRendersWithLike(t, "Nested path", v, RenderOptions{IncludeFields: []string{"User.Name", "ID"}},
+		`render.account{ID:1, User:render.user{Name:"ann", ...}, ...}`)
+	assertRendersWithLike(t, "Through slices and pointers", v, RenderOptions{IncludeFields: []string{"Friends.Email", "Owner.Name"}},
+		`render.account{Friends:[]render.user{render.user{Email:"b@x", ...}}, Owner:(*render.user){Name:"cat", ...}, ...}`)
+	assertRendersWithLike(t, "Slice of structs", []user{{"ann", "a@x"}}, RenderOptions{IncludeFields: []string{"Email"}},
+		`[]render.user{render.user{Email:"a@x", ...}}`)
+	assertRendersWithLike(t, "All fields", user{"ann", "a@x"}, RenderOptions{IncludeFields: []string{"Name", "Email"}},
+		`render.user{Name:"ann", Email:"a@x"}`)
+	assertRendersWithLike(t, "No match", v, RenderOptions{IncludeFields: []string{"Missing"}},
+		`render.account{...}`)
+	assertRendersWithLike(t, "Empty", user{"ann", "a@x"}, RenderOptions{IncludeFields: []string{}},
+		`render.user{Name:"ann", Email:"a@x"}`)
+}
+
+func TestRenderColor(t *testing.T) {
+	type testStruct struct {
+		Name string
+		Self *testStruct
+	}
+
+	opts := RenderOptions{Color: true}
+	v := &testStruct{Name: "x"}
+	v.Self = v
+
+	assertRendersWithLike(t, "Struct", v, opts,
+		"\x1b[36m(*render.testStruct)\x1b[0m{Name:\x1b[32m\"x\"\x1b[0m, Self:\x1b[31m<REC(*render.testStruct)>\x1b[0m}")
+	assertRendersWithLike(t, "Slice", []int{1}, opts, "\x1b[36m[]int\x1b[0m{1}")
+	assertRendersWithLike(t, "Compact", v, RenderOptions{Color: true, OmitTypePrefix: true},
+		"{Name:\x1b[32m\"x\"\x1b[0m, Self:\x1b[31m<REC>\x1b[0m}")
+	assertRendersWithLike(t, "Truncated", []string{"abc"}, RenderOptions{Color: true, MaxTotalBytes: 20},
+		"\x1b[36m[]string\x1b[0m{\x1b[0m...<truncated>")
+	if act := RenderWith(v, RenderOptions{}); strings.Contains(act, "\x1b") {
+		t.Errorf("escape codes without Color: %q", act)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderTo(&buf, v, opts); err != nil {
+		t.Fatal(err)
+	}
+	if act := buf.String(); strings.Contains(act, "\x1b") {
+		t.Errorf("escape codes written to a non-terminal: %q", act)
+	}
+}
+
+func TestRenderNamedStringInterfaceKeys(t *testing.T) {
+	type myStringType string
+
+	m := map[any]struct{}{
+		"foo": {}, myStringType("foo"): {}, 3: {}, myStringType("bar"): {}, "bar": {}, 1: {},
+	}
+	// Keys are grouped by their dynamic type's name, then by value.
+	exp := `map[any]struct {}{1:{}, 3:{}, ` +
+		`render.myStringType("bar"):{}, render.myStringType("foo"):{}, ` +
+		`"bar":{}, "foo":{}}`
+	for i := 0; i < 10; i++ {
+		assertRendersLike(t, "Mixed keys", m, exp)
+	}
+	assertRendersLike(t, "Map values", map[string]any{"k": myStringType("v")},
+		`map[string]any{"k":render.myStringType("v")}`)
+}
+
+func TestRenderShowAddresses(t *testing.T) {
+	type testStruct struct {
+		A int
+		P *int
+	}
+
+	i := 1
+	pi := &i
+	opts := RenderOptions{ShowAddresses: true}
+
+	assertRendersWithLike(t, "Pointer to struct", &testStruct{2, &i}, opts,
+		`(*render.testStruct @PTR){A:2, P:(*int @PTR)(1)}`)
+	assertRendersWithLike(t, "Pointer to int", &i, opts, `(*int @PTR)(1)`)
+	assertRendersWithLike(t, "Pointer to pointer", &pi, opts, `(**int @PTR)(1)`)
+	assertRendersWithLike(t, "Nil pointer", testStruct{}, opts, `render.testStruct{A:0, P:(*int)(nil)}`)
+	assertRendersWithLike(t, "Slice of pointers", []*int{&i}, opts, `[]*int{(*int @PTR)(1)}`)
+	assertRendersWithLike(t, "Compact", &i, RenderOptions{ShowAddresses: true, OmitTypePrefix: true}, `1`)
+	assertRendersLike(t, "Without option", &i, `(*int)(1)`)
+
+	// Addresses are only shown for the value their pointer points to.
+	assertRendersWithLike(t, "Interface elements", []any{&i, 2}, RenderOptions{ShowAddresses: true, ShowDynamicTypes: true},
+		`[]any{(*int @PTR)(1), int(2)}`)
+}
+
+func TestRenderElideElemTypes(t *testing.T) {
+	type testStruct struct{ A int }
+	type myInt int
+
+	opts := RenderOptions{ElideElemTypes: true}
+
+	assertRendersWithLike(t, "Pointer slice", []*testStruct{{1}, nil, {2}}, opts,
+		`[]*render.testStruct{{A:1}, nil, {A:2}}`)
+	assertRendersWithLike(t, "Struct array", [2]testStruct{{1}, {2}}, opts, `[2]render.testStruct{{A:1}, {A:2}}`)
+	assertRendersWithLike(t, "Named scalars", []myInt{1, 2}, opts, `[]render.myInt{1, 2}`)
+	assertRendersWithLike(t, "Nested", [][]*testStruct{{{1}}}, opts, `[][]*render.testStruct{{{A:1}}}`)
+	assertRendersWithLike(t, "Interface slice", []any{testStruct{1}, &testStruct{2}, myInt(3)}, opts,
+		`[]any{render.testStruct{A:1}, (*render.testStruct){A:2}, render.myInt(3)}`)
+	assertRendersWithLike(t, "Runs", []*testStruct{{1}, {1}, {1}}, RenderOptions{ElideElemTypes: true, MinRunLength: 2},
+		`[]*render.testStruct{{A:1} x3}`)
+	i := 1
+	assertRendersWithLike(t, "Fields keep their types", []struct{ P *int }{{&i}}, opts,
+		`[]struct { P *int }{{(*int)(1)}}`)
+	assertRendersLike(t, "Without option", []*testStruct{{1}},
+		`[]*render.testStruct{(*render.testStruct){A:1}}`)
+}
+
+func TestRenderMixedContainerCycles(t *testing.T) {
+	type node struct{ M map[string]any }
+
+	m := map[string]any{}
+	m["s"] = []any{m}
+	act := Render(m)
+	if exp := `map[string]any{"s":[]any{<REC(map[string]any)>}}`; act != exp {
+		t.Errorf("Expected: %s\nActual  : %s", exp, act)
+	}
+	if n := strings.Count(act, "<REC"); n != 1 {
+		t.Errorf("expected a single recursion marker, found %d: %s", n, act)
+	}
+
+	n := &node{M: map[string]any{}}
+	n.M["n"] = []any{n}
+	assertRendersLike(t, "Pointer, map, and slice", n,
+		`(*render.node){M:map[string]any{"n":[]any{<REC(*render.node)>}}}`)
+}
+
+func TestRenderSQLNulls(t *testing.T) {
+	type nullish struct {
+		Valid bool
+		Value []int
+	}
+	type record struct {
+		Name sql.NullString
+		Age  sql.NullInt64
+	}
+
+	opts := RenderOptions{SQLNulls: true}
+
+	assertRendersWithLike(t, "Valid string", sql.NullString{String: "x", Valid: true}, opts, `sql.NullString("x")`)
+	assertRendersWithLike(t, "Invalid string", sql.NullString{String: "x"}, opts, `sql.NullString(NULL)`)
+	assertRendersWithLike(t, "Valid int", sql.NullInt64{Int64: 5, Valid: true}, opts, `sql.NullInt64(5)`)
+	assertRendersWithLike(t, "Invalid int", sql.NullInt64{}, opts, `sql.NullInt64(NULL)`)
+	assertRendersWithLike(t, "Fields", record{Age: sql.NullInt64{Int64: 5, Valid: true}}, opts,
+		`render.record{Name:sql.NullString(NULL), Age:sql.NullInt64(5)}`)
+	assertRendersWithLike(t, "Pointer", &sql.NullString{String: "x", Valid: true}, opts, `(*sql.NullString)("x")`)
+	assertRendersWithLike(t, "Same shape", nullish{true, []int{1}}, opts, `render.nullish({1})`)
+	assertRendersWithLike(t, "Compact", record{}, RenderOptions{SQLNulls: true, OmitTypePrefix: true},
+		`{Name:NULL, Age:NULL}`)
+	assertRendersLike(t, "Without option", sql.NullString{String: "x", Valid: true},
+		`sql.NullString{String:"x", Valid:true}`)
+}
+
+func TestRenderNetTypes(t *testing.T) {
+	type host struct {
+		IP   net.IP
+		addr netip.Addr
+	}
+
+	_, ipNet, _ := net.ParseCIDR("10.0.0.0/8")
+
+	assertRendersLike(t, "IPv4", net.ParseIP("192.168.0.1"), `net.IP(192.168.0.1)`)
+	assertRendersLike(t, "IPv6", net.ParseIP("2001:db8::1"), `net.IP(2001:db8::1)`)
+	assertRendersLike(t, "Nil IP", net.IP(nil), `net.IP(nil)`)
+	assertRendersLike(t, "IPNet", ipNet, `(*net.IPNet)(10.0.0.0/8)`)
+	assertRendersLike(t, "Addr", netip.MustParseAddr("fe80::1"), `netip.Addr(fe80::1)`)
+	assertRendersLike(t, "AddrPort", netip.MustParseAddrPort("1.2.3.4:80"), `netip.AddrPort(1.2.3.4:80)`)
+	assertRendersLike(t, "Prefix", netip.MustParsePrefix("1.2.3.0/24"), `netip.Prefix(1.2.3.0/24)`)
+	assertRendersLike(t, "Fields", host{net.IPv4(1, 2, 3, 4), netip.MustParseAddr("::1")},
+		`render.host{IP:net.IP(1.2.3.4), addr:netip.Addr(::1)}`)
+}
+
+func TestRenderSortStructFields(t *testing.T) {
+	type inner struct{ Z, A int }
+	type testStruct struct {
+		Name   string
+		age    int
+		Inner  inner
+		Alias  string
+		inner2 bool
+	}
+
+	v := testStruct{"n", 3, inner{1, 2}, "a", true}
+	assertRendersLike(t, "Declaration order", v,
+		`render.testStruct{Name:"n", age:3, Inner:render.inner{Z:1, A:2}, Alias:"a", inner2:true}`)
+	assertRendersWithLike(t, "Sorted", v, RenderOptions{SortStructFields: true},
+		`render.testStruct{age:3, Alias:"a", Inner:render.inner{A:2, Z:1}, inner2:true, Name:"n"}`)
+
+	type embedded struct{ B, A int }
+	type flattened struct {
+		embedded
+		C int
+	}
+	assertRendersWithLike(t, "Flattened", flattened{embedded{1, 2}, 3}, RenderOptions{SortStructFields: true, FlattenEmbedded: true},
+		`render.flattened{A:2, B:1, C:3}`)
+}
+
+func TestRenderNilChanAndFunc(t *testing.T) {
+	type testStruct struct {
+		C chan int
+		F func()
+	}
+
+	var nilChan chan int
+	var nilFunc func()
+	assertRendersLike(t, "Nil chan", nilChan, `(chan int)(nil)`)
+	assertRendersLike(t, "Chan", make(chan int), `(chan int)(PTR)`)
+	assertRendersLike(t, "Nil func", nilFunc, `(func())(nil)`)
+	assertRendersLike(t, "Func", func() {}, `(func())(PTR)`)
+	assertRendersLike(t, "Nil unsafe.Pointer", unsafe.Pointer(nil), `unsafe.Pointer(nil)`)
+	assertRendersLike(t, "Fields", testStruct{}, `render.testStruct{C:(chan int)(nil), F:(func())(nil)}`)
+	assertRendersWithLike(t, "Compact", testStruct{F: func() {}}, RenderOptions{OmitTypePrefix: true}, `{C:nil, F:PTR}`)
+	assertRendersWithLike(t, "Capacity", nilChan, RenderOptions{ChanCapacity: true}, `(chan int, cap=0)(nil)`)
+}
+
+func TestRenderTimeUTC(t *testing.T) {
+	utc := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	zoned := utc.In(time.FixedZone("EST", -5*60*60))
+	layout := RenderOptions{TimeLayout: time.RFC3339}
+
+	assertRendersWithLike(t, "UTC", utc, RenderOptions{TimeUTC: true}, `time.Time{2000-01-01 12:00:00 +0000 UTC}`)
+	assertRendersWithLike(t, "Zoned", zoned, RenderOptions{TimeUTC: true}, `time.Time{2000-01-01 12:00:00 +0000 UTC}`)
+	assertRendersLike(t, "Zoned without option", zoned, `time.Time{2000-01-01 07:00:00 -0500 EST}`)
+	assertRendersWithLike(t, "Layout", zoned, RenderOptions{TimeLayout: time.RFC3339, TimeUTC: true}, `time.Time{2000-01-01T12:00:00Z}`)
+	assertRendersWithLike(t, "Layout without option", zoned, layout, `time.Time{2000-01-01T07:00:00-05:00}`)
+	assertRendersWithLike(t, "Zero", time.Time{}, RenderOptions{TimeUTC: true}, `time.Time{0}`)
+}
+
+func TestRenderSpecialFloats(t *testing.T) {
+	type testStruct struct {
+		Zero, NegZero, PosInf, NegInf, NaN float64
+	}
+
+	negZero := math.Copysign(0, -1)
+	assertRendersLike(t, "Negative zero", negZero, `-0`)
+	assertRendersLike(t, "Positive zero", 0.0, `0`)
+	assertRendersLike(t, "Positive infinity", math.Inf(1), `+Inf`)
+	assertRendersLike(t, "Negative infinity", math.Inf(-1), `-Inf`)
+	assertRendersLike(t, "NaN", math.NaN(), `NaN`)
+	assertRendersLike(t, "Float32", []float32{float32(negZero), float32(math.Inf(1))}, `[]float32{-0, +Inf}`)
+	assertRendersLike(t, "Fields", testStruct{0, negZero, math.Inf(1), math.Inf(-1), math.NaN()},
+		`render.testStruct{Zero:0, NegZero:-0, PosInf:+Inf, NegInf:-Inf, NaN:NaN}`)
+	assertRendersWithLike(t, "No exponent", []float64{negZero, math.NaN()}, RenderOptions{NoExponent: true},
+		`[]float64{-0, NaN}`)
+}
+
+func TestRenderReflectType(t *testing.T) {
+	type testStruct struct {
+		T reflect.Type
+		t reflect.Type
+	}
+
+	mapType := reflect.TypeOf(map[string]int{})
+	assertRendersLike(t, "Concrete type", mapType, `reflect.Type(map[string]int)`)
+	assertRendersLike(t, "Nil type", testStruct{}, `render.testStruct{T:reflect.Type(nil), t:reflect.Type(nil)}`)
+	assertRendersLike(t, "Fields", testStruct{mapType, reflect.TypeOf(testStruct{})},
+		`render.testStruct{T:reflect.Type(map[string]int), t:reflect.Type(render.testStruct)}`)
+	assertRendersLike(t, "Slice", []reflect.Type{mapType, nil},
+		`[]reflect.Type{reflect.Type(map[string]int), reflect.Type(nil)}`)
+	assertRendersLike(t, "Interface slice", []any{reflect.TypeOf(0)}, `[]any{reflect.Type(int)}`)
+}
+
+func TestRenderMaxNodes(t *testing.T) {
+	type node struct {
+		ID       int
+		Children []*node
+	}
+
+	// A tree of 1 + 100 + 10000 nodes.
+	root := &node{}
+	for i := 0; i < 100; i++ {
+		child := &node{ID: i}
+		for j := 0; j < 100; j++ {
+			child.Children = append(child.Children, &node{ID: j})
+		}
+		root.Children = append(root.Children, child)
+	}
+
+	act := RenderWith(root, RenderOptions{MaxNodes: 1000})
+	if !strings.Contains(act, "...<node limit>") {
+		t.Errorf("node limit marker missing: %s", act)
+	}
+	if !strings.HasSuffix(act, "...<node limit>}}}}") {
+		t.Errorf("output should end where rendering stopped: %s", act[len(act)-50:])
+	}
+
+	assertRendersWithLike(t, "Slice", []int{1, 2, 3, 4}, RenderOptions{MaxNodes: 3}, `[]int{1, 2, ...<node limit>}`)
+	assertRendersWithLike(t, "Within limit", []int{1, 2, 3}, RenderOptions{MaxNodes: 4}, `[]int{1, 2, 3}`)
+	assertRendersWithLike(t, "Byte limit first", []string{"aaaaaaaaaa", "b", "c"}, RenderOptions{MaxNodes: 3, MaxTotalBytes: 15},
+		`[]string{"aaaaa...<truncated>`)
+	assertRendersWithLike(t, "Node limit first", []string{"a", "b", "c"}, RenderOptions{MaxNodes: 2, MaxTotalBytes: 100},
+		`[]string{"a", ...<node limit>}`)
+}
+
+func collidingKeyA(n int) any {
+	type key int
+	return key(n)
+}
+
+func collidingKeyB(n int) any {
+	type key int
+	return key(n)
+}
+
+func TestRenderCollidingKeysOrder(t *testing.T) {
+	type key struct{ A int }
+
+	// Distinct types with the same name are ordered consistently.
+	exp := Render(map[any]int{collidingKeyA(1): 1, collidingKeyB(1): 2})
+	for i := 0; i < 100; i++ {
+		if act := Render(map[any]int{collidingKeyA(1): 1, collidingKeyB(1): 2}); act != exp {
+			t.Fatalf("unstable order:\nFirst: %s\nLater: %s", exp, act)
+		}
+	}
+
+	// Keys that don't fit in static boxes are allocated on the heap, so their
+	// order mustn't depend on their addresses.
+	for i := 0; i < 100; i++ {
+		assertRendersLike(t, "Heap keys", map[any]int{collidingKeyB(1000): 2, collidingKeyA(1000): 1},
+			`map[any]int{render.key(1000):1, render.key(1000):2}`)
+	}
+
+	// Keys rendered identically by a handler are still ordered by value.
+	opts := RenderOptions{TypeHandlers: map[reflect.Type]func(any) string{
+		reflect.TypeOf(key{}): func(any) string { return "same" },
+	}}
+	m := map[key]int{{3}: 3, {1}: 1, {2}: 2}
+	for i := 0; i < 10; i++ {
+		assertRendersWithLike(t, "Handler collision", m, opts,
+			`map[render.key]int{render.key(same):1, render.key(same):2, render.key(same):3}`)
+	}
+}
+
+func TestRenderMapOrder(t *testing.T) {
+	m := map[string]int{"c": 3, "a": 1, "b": 2, "d": 4}
+
+	assertRendersWithLike(t, "Sorted", m, RenderOptions{MapOrder: Sorted}, `map[string]int{"a":1, "b":2, "c":3, "d":4}`)
+	if act, exp := RenderWith(m, RenderOptions{MapOrder: Sorted}), Render(m); act != exp {
+		t.Errorf("Sorted should match the default:\nExpected: %s\nActual  : %s", exp, act)
+	}
+
+	// Unsorted output contains the same entries in some order.
+	act := RenderWith(m, RenderOptions{MapOrder: Unsorted})
+	entries := strings.Split(strings.TrimSuffix(strings.TrimPrefix(act, "map[string]int{"), "}"), ", ")
+	sort.Strings(entries)
+	if exp := []string{`"a":1`, `"b":2`, `"c":3`, `"d":4`}; !reflect.DeepEqual(entries, exp) {
+		t.Errorf("unexpected unsorted entries: %s", act)
+	}
+}
+
+func TestRenderPointerInterfaceKeys(t *testing.T) {
+	a, b := 10, 20
+	m := map[any]struct{}{2: {}, &b: {}, 1: {}, &a: {}}
+
+	// Pointers are grouped by their dynamic type and ordered by address, so
+	// that the output doesn't depend on map iteration order.
+	first, second := "10", "20"
+	if uintptr(unsafe.Pointer(&b)) < uintptr(unsafe.Pointer(&a)) {
+		first, second = second, first
+	}
+	exp := "map[any]struct {}{(*int)(" + first + "):{}, (*int)(" + second + "):{}, 1:{}, 2:{}}"
+	for i := 0; i < 20; i++ {
+		assertRendersLike(t, "Mixed keys", m, exp)
+	}
+}
+
+type embeddedError struct {
+	error
+	Name string
+}
+
+func TestRenderEmbeddedError(t *testing.T) {
+	opts := RenderOptions{Errors: true}
+
+	assertRendersWithLike(t, "Nil", embeddedError{Name: "a"}, opts,
+		`render.embeddedError{error:error(nil), Name:"a"}`)
+	assertRendersWithLike(t, "Populated", embeddedError{errors.New("boom"), "b"}, opts,
+		`render.embeddedError{error:(*errors.errorString)("boom"), Name:"b"}`)
+	assertRendersWithLike(t, "Pointer", &embeddedError{errors.New("boom"), "c"}, opts,
+		`(*render.embeddedError){error:(*errors.errorString)("boom"), Name:"c"}`)
+	assertRendersWithLike(t, "Flattened", embeddedError{Name: "d"}, RenderOptions{Errors: true, FlattenEmbedded: true},
+		`render.embeddedError{error:error(nil), Name:"d"}`)
+	assertRendersWithLike(t, "Stringers", embeddedError{Name: "e"}, RenderOptions{Errors: true, Stringers: true},
+		`render.embeddedError{error:error(nil), Name:"e"}`)
+}
+
+func TestRenderHighlight(t *testing.T) {
+	type address struct {
+		Street string
+		Zip    string
+	}
+	type user struct {
+		Name string
+		Addr *address
+		Tags map[string]int
+	}
+	type team struct {
+		Users []user
+	}
+	v := team{Users: []user{
+		{Name: "a", Addr: &address{"Main", "12345"}},
+		{Name: "b", Tags: map[string]int{"x": 1, "y": 2}},
+	}}
+
+	for _, tc := range []struct {
+		name, path, expected string
+	}{
+		{"Field", "Users[0].Addr.Zip", `render.team{Users:[]render.user{render.user{Name:"a", Addr:(*render.address){Street:"Main", Zip:>>> "12345" <<<}, Tags:map[string]int(nil)}, render.user{Name:"b", Addr:(*render.address)(nil), Tags:map[string]int{"x":1, "y":2}}}}`},
+		{"Pointer", "Users[0].Addr", `render.team{Users:[]render.user{render.user{Name:"a", Addr:>>> (*render.address){Street:"Main", Zip:"12345"} <<<, Tags:map[string]int(nil)}, render.user{Name:"b", Addr:(*render.address)(nil), Tags:map[string]int{"x":1, "y":2}}}}`},
+		{"Index", "Users[1]", `render.team{Users:[]render.user{render.user{Name:"a", Addr:(*render.address){Street:"Main", Zip:"12345"}, Tags:map[string]int(nil)}, >>> render.user{Name:"b", Addr:(*render.address)(nil), Tags:map[string]int{"x":1, "y":2}} <<<}}`},
+		{"MapKey", `Users[1].Tags."y"`, `render.team{Users:[]render.user{render.user{Name:"a", Addr:(*render.address){Street:"Main", Zip:"12345"}, Tags:map[string]int(nil)}, render.user{Name:"b", Addr:(*render.address)(nil), Tags:map[string]int{"x":1, "y":>>> 2 <<<}}}}`},
+		{"Unmatched", "Users[2].Name", Render(v)},
+	} {
+		if actual := RenderHighlight(v, tc.path); actual != tc.expected {
+			t.Errorf("[%s] did not match expectations:\nExpected: %s\nActual  : %s", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestRenderHeadTail(t *testing.T) {
+	s := make([]int, 1000)
+	for i := range s {
+		s[i] = i
+	}
+	opts := RenderOptions{HeadTail: 3}
+
+	assertRendersWithLike(t, "Long slice", s, opts,
+		`[]int{0, 1, 2, ...(+994 more of 1000), 997, 998, 999}`)
+	assertRendersWithLike(t, "Array", [7]int{0, 1, 2, 3, 4, 5, 6}, opts,
+		`[7]int{0, 1, 2, ...(+1 more of 7), 4, 5, 6}`)
+	assertRendersWithLike(t, "Exactly twice", s[:6], opts,
+		`[]int{0, 1, 2, 3, 4, 5}`)
+	assertRendersWithLike(t, "Short slice", s[:2], opts,
+		`[]int{0, 1}`)
+	assertRendersWithLike(t, "Indices", s[:10], RenderOptions{HeadTail: 1, ShowIndices: true},
+		`[]int{0:0, ...(+8 more of 10), 9:9}`)
+}
+
+type methodsType struct{ A int }
+
+func (methodsType) String() string   { return "" }
+func (*methodsType) Error() string   { return "" }
+func (methodsType) unexported() bool { return false }
+
+func TestRenderShowMethods(t *testing.T) {
+	opts := RenderOptions{ShowMethods: true}
+
+	assertRendersWithLike(t, "Value", methodsType{1}, opts,
+		`render.methodsType{A:1} /* methods: Error, String */`)
+	assertRendersWithLike(t, "Pointer", &methodsType{1}, opts,
+		`(*render.methodsType){A:1} /* methods: Error, String */`)
+	assertRendersWithLike(t, "No methods", struct{ A int }{1}, opts,
+		`struct { A int }{1}`)
+	assertRendersWithLike(t, "Nil", nil, opts, `nil`)
+	assertRendersLike(t, "Without option", methodsType{1}, `render.methodsType{A:1}`)
+}
+
+// TestRenderConcurrent is meant to be run with -race.
+func TestRenderConcurrent(t *testing.T) {
+	type node struct {
+		Name string
+		Tags map[string][]int
+		Next *node
+	}
+	shared := &node{Name: "shared", Tags: map[string][]int{"b": {2}, "a": {1}}}
+	shared.Next = shared
+	exp := Render(shared)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			distinct := &node{Name: strconv.Itoa(i), Tags: map[string][]int{"x": {i}}}
+			distinctExp := fmt.Sprintf(`(*render.node){Name:"%d", Tags:map[string][]int{"x":{%d}}, Next:(*render.node)(nil)}`, i, i)
+			for j := 0; j < 50; j++ {
+				if act := Render(shared); act != exp {
+					t.Errorf("shared value:\nExpected: %s\nActual  : %s", exp, act)
+				}
+				if act := Render(distinct); act != distinctExp {
+					t.Errorf("distinct value:\nExpected: %s\nActual  : %s", distinctExp, act)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+type stringerList []int
+
+func (l stringerList) String() string { return fmt.Sprintf("%d items", len(l)) }
+
+func TestRenderStringerLeafOnly(t *testing.T) {
+	opts := RenderOptions{Stringers: true, StringerLeafOnly: true}
+
+	assertRendersWithLike(t, "Slice", stringerList{1, 2}, opts,
+		`render.stringerList{1, 2}`)
+	assertRendersWithLike(t, "Scalar", plainStringer(3), opts,
+		`render.plainStringer("plain#3")`)
+	assertRendersWithLike(t, "Scalar in slice", []plainStringer{1}, opts,
+		`[]render.plainStringer{render.plainStringer("plain#1")}`)
+	assertRendersWithLike(t, "Slice without option", stringerList{1, 2}, RenderOptions{Stringers: true},
+		`render.stringerList("2 items")`)
+}
+
+func TestRenderFieldFilter(t *testing.T) {
+	type inner struct {
+		ID    int
+		Token string `render:"deprecated"`
+	}
+	type outer struct {
+		ID    int
+		Inner inner
+		List  []inner
+		Old   string `render:"deprecated"`
+	}
+	v := outer{1, inner{2, "a"}, []inner{{3, "b"}}, "old"}
+
+	var paths []string
+	byName := RenderOptions{FieldFilter: func(path string, _ reflect.StructField, _ reflect.Value) bool {
+		paths = append(paths, path)
+		return path != "Inner.ID"
+	}}
+	assertRendersWithLike(t, "By path", v, byName,
+		`render.outer{ID:1, Inner:render.inner{Token:"a"}, List:[]render.inner{render.inner{ID:3, Token:"b"}}, Old:"old"}`)
+	if exp := []string{"ID", "Inner", "Inner.ID", "Inner.Token", "List", "List[0].ID", "List[0].Token", "Old"}; !reflect.DeepEqual(paths, exp) {
+		t.Errorf("unexpected paths:\nExpected: %q\nActual  : %q", exp, paths)
+	}
+
+	byTag := RenderOptions{FieldFilter: func(_ string, f reflect.StructField, _ reflect.Value) bool {
+		return !hasTagOption(f, "deprecated")
+	}}
+	assertRendersWithLike(t, "By tag", v, byTag,
+		`render.outer{ID:1, Inner:render.inner{ID:2}, List:[]render.inner{render.inner{ID:3}}}`)
+}
+
+func TestRenderQualifyComplexKeys(t *testing.T) {
+	type myComplex complex128
+	opts := RenderOptions{QualifyMapKeyTypes: true}
+
+	assertRendersWithLike(t, "complex64 keys", map[complex64]string{1: "a", 2i: "b"}, opts,
+		`map[complex64]string{complex64(0+2i):"b", complex64(1+0i):"a"}`)
+	assertRendersWithLike(t, "complex128 keys", map[complex128]complex128{1: 2i}, opts,
+		`map[complex128]complex128{complex128(1+0i):(0+2i)}`)
+	assertRendersWithLike(t, "Values", map[string]complex128{"a": 1 + 2i}, opts, `map[string]complex128{"a":(1+2i)}`)
+	assertRendersWithLike(t, "Standalone", complex64(complex(1, 2)), opts, `(1+2i)`)
+	assertRendersWithLike(t, "Named", myComplex(1+2i), opts, `render.myComplex((1+2i))`)
+	assertRendersWithLike(t, "Compact", map[complex64]bool{1: true}, RenderOptions{QualifyMapKeyTypes: true, OmitTypePrefix: true},
+		`{(1+0i):true}`)
+
+	assertRendersLike(t, "Bare complex128", complex(3, 0.14), `(3+0.14i)`)
+	assertRendersLike(t, "Bare map keys", map[complex128]complex128{1: 2i}, `map[complex128]complex128{(1+0i):(0+2i)}`)
+	assertRendersLike(t, "Named without option", myComplex(1+2i), `render.myComplex((1+2i))`)
+}
+
+func TestRenderRecursionPaths(t *testing.T) {
+	type node struct {
+		Name     string
+		Parent   *node
+		Children []*node
+	}
+	root := &node{Name: "root"}
+	child := &node{Name: "child", Parent: root}
+	root.Children = []*node{child}
+	child.Children = []*node{child}
+	opts := RenderOptions{RecursionPaths: true}
+
+	assertRendersWithLike(t, "Tree", root, opts,
+		`(*render.node){Name:"root", Parent:(*render.node)(nil), Children:[]*render.node{(*render.node){Name:"child", Parent:<REC -> .>, Children:[]*render.node{<REC -> .Children[0]>}}}}`)
+	assertRendersWithLike(t, "Nested", struct{ I *node }{root}, opts,
+		`struct { I *render.node }{(*render.node){Name:"root", Parent:(*render.node)(nil), Children:[]*render.node{(*render.node){Name:"child", Parent:<REC -> .I>, Children:[]*render.node{<REC -> .I.Children[0]>}}}}}`)
+	assertRendersLike(t, "Without option", child,
+		`(*render.node){Name:"child", Parent:(*render.node){Name:"root", Parent:(*render.node)(nil), Children:[]*render.node{<REC()>}}, Children:[]*render.node{<REC()>}}`)
+}
+
+func TestRenderHumanDurations(t *testing.T) {
+	opts := RenderOptions{HumanDurations: true}
+
+	assertRendersWithLike(t, "Value", 90*time.Second, opts, `time.Duration(1m30s)`)
+	assertRendersWithLike(t, "Map keys", map[time.Duration]int{time.Minute: 2, time.Second: 1, 90 * time.Millisecond: 3, -time.Hour: 4}, opts,
+		`map[time.Duration]int{-1h0m0s:4, 90ms:3, 1s:1, 1m0s:2}`)
+	assertRendersWithLike(t, "Field", struct{ d time.Duration }{time.Millisecond}, opts,
+		`struct { d time.Duration }{d:time.Duration(1ms)}`)
+	assertRendersWithLike(t, "Precedence over Stringers", time.Second, RenderOptions{HumanDurations: true, Stringers: true},
+		`time.Duration(1s)`)
+	assertRendersLike(t, "Without option", map[time.Duration]int{time.Second: 1}, `map[time.Duration]int{1000000000:1}`)
+}
+
+func TestRenderCycleIDs(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	a := &node{Name: "a"}
+	b := &node{Name: "b", Next: a}
+	a.Next = b
+	self := &node{Name: "self"}
+	self.Next = self
+	opts := RenderOptions{CycleIDs: true}
+
+	assertRendersWithLike(t, "Self", self, opts,
+		`#1 (*render.node){Name:"self", Next:<CYCLE #1>}`)
+	assertRendersWithLike(t, "Pair", []*node{b, self}, opts,
+		`[]*render.node{#1 (*render.node){Name:"b", Next:(*render.node){Name:"a", Next:<CYCLE #1>}}, #2 (*render.node){Name:"self", Next:<CYCLE #2>}}`)
+	assertRendersWithLike(t, "Shared target", []*node{self, self}, opts,
+		`[]*render.node{#1 (*render.node){Name:"self", Next:<CYCLE #1>}, (*render.node){Name:"self", Next:<CYCLE #1>}}`)
+	assertRendersLike(t, "Without option", self,
+		`(*render.node){Name:"self", Next:<REC(*render.node)>}`)
+}
+
+type uuid [16]byte
+
+func TestRenderUUIDs(t *testing.T) {
+	id := [16]byte{0x55, 0x0e, 0x84, 0x00, 0xe2, 0x9b, 0x41, 0xd4, 0xa7, 0x16, 0x44, 0x66, 0x55, 0x44, 0x00, 0x00}
+	opts := RenderOptions{UUIDs: true}
+
+	assertRendersWithLike(t, "Array", id, RenderOptions{UUIDs: true, ByteAlias: true},
+		`[16]byte(550e8400-e29b-41d4-a716-446655440000)`)
+	type record struct{ ID [16]byte }
+	assertRendersWithLike(t, "Field", record{id}, opts,
+		`render.record{ID:[16]uint8(550e8400-e29b-41d4-a716-446655440000)}`)
+	assertRendersWithLike(t, "Named", uuid(id), opts,
+		`render.uuid(550e8400-e29b-41d4-a716-446655440000)`)
+	assertRendersWithLike(t, "Map key", map[uuid]int{uuid(id): 1}, opts,
+		`map[render.uuid]int{render.uuid(550e8400-e29b-41d4-a716-446655440000):1}`)
+	assertRendersWithLike(t, "Other lengths", [4]byte{1, 2, 3, 4}, opts, `[4]uint8{1, 2, 3, 4}`)
+	assertRendersWithLike(t, "Slices", id[:], opts, `[]uint8{85, 14, 132, 0, 226, 155, 65, 212, 167, 22, 68, 102, 85, 68, 0, 0}`)
+}
+
+func TestRenderFloatFormat(t *testing.T) {
+	v := []any{1234.5678, complex(1234.5678, -0.5)}
+
+	assertRendersWithLike(t, "g", v, RenderOptions{FloatFormat: 'g', FloatPrecision: 3},
+		`[]any{1.23e+03, (1.23e+03-0.5i)}`)
+	assertRendersWithLike(t, "f", v, RenderOptions{FloatFormat: 'f', FloatPrecision: 3},
+		`[]any{1234.568, (1234.568-0.500i)}`)
+	assertRendersWithLike(t, "e", v, RenderOptions{FloatFormat: 'e', FloatPrecision: 3},
+		`[]any{1.235e+03, (1.235e+03-5.000e-01i)}`)
+	assertRendersWithLike(t, "Shortest", v, RenderOptions{FloatFormat: 'e'},
+		`[]any{1.2345678e+03, (1.2345678e+03-5e-01i)}`)
+	assertRendersWithLike(t, "Overrides NoExponent", 1e6, RenderOptions{NoExponent: true, FloatFormat: 'e'},
+		`1e+06`)
+	assertRendersLike(t, "Default", []any{1e21, complex(math.Inf(1), math.NaN())},
+		`[]any{1e+21, (+Inf+NaNi)}`)
+}
+
+func TestRenderMaxLengths(t *testing.T) {
+	s := make([]int, 150)
+	m := make(map[string]int, len(s))
+	for i := range s {
+		s[i] = i
+		m[fmt.Sprintf("k%03d", i)] = i
+	}
+
+	assertRendersWithLike(t, "Slice", s, RenderOptions{MaxSliceLen: 2},
+		`[]int{0, 1, ...(+148 more of 150)}`)
+	assertRendersWithLike(t, "Array", [3]int{1, 2, 3}, RenderOptions{MaxSliceLen: 1},
+		`[3]int{1, ...(+2 more of 3)}`)
+	assertRendersWithLike(t, "Map", m, RenderOptions{MaxMapLen: 2},
+		`map[string]int{"k000":0, "k001":1, ...(+148 more of 150)}`)
+	assertRendersWithLike(t, "Within limits", map[string]int{"a": 1}, RenderOptions{MaxMapLen: 1, MaxSliceLen: 1},
+		`map[string]int{"a":1}`)
+	assertRendersWithLike(t, "Indented", s[:3], RenderOptions{MaxSliceLen: 1, Indent: "  "},
+		"[]int{\n  0,\n  ...(+2 more of 3),\n}")
+}
+
+func TestRenderMaxLinkedLen(t *testing.T) {
+	type N struct {
+		Next *N
+		Val  int
+	}
+	var head *N
+	for i := 999; i >= 0; i-- {
+		head = &N{head, i}
+	}
+	opts := RenderOptions{MaxLinkedLen: 3}
+
+	assertRendersWithLike(t, "Long list", head, opts,
+		`(*render.N){Next:(*render.N){Next:(*render.N){Next:...(chain continues), Val:2}, Val:1}, Val:0}`)
+	assertRendersWithLike(t, "Short list", &N{Val: 1}, opts,
+		`(*render.N){Next:(*render.N)(nil), Val:1}`)
+	assertRendersWithLike(t, "Separate chains", []*N{head.Next.Next.Next, head}, RenderOptions{MaxLinkedLen: 1},
+		`[]*render.N{(*render.N){Next:...(chain continues), Val:3}, (*render.N){Next:...(chain continues), Val:0}}`)
+
+	if r := Render(head); strings.Count(r, "Val:") != 1000 {
+		t.Errorf("without option, the whole list should be rendered: %s", r)
+	}
+}
+
+func TestRenderOnNode(t *testing.T) {
+	type inner struct{ A int }
+	type outer struct {
+		In   *inner
+		List []string
+		M    map[string]bool
+	}
+	v := outer{&inner{1}, []string{"x", "y", "z"}, map[string]bool{"k": true}}
+
+	var nodes []string
+	opts := RenderOptions{OnNode: func(path string, v reflect.Value) {
+		nodes = append(nodes, path+" "+v.Type().String())
+	}}
+	exp := Render(v)
+	if act := RenderWith(v, opts); act != exp {
+		t.Errorf("OnNode changed the output:\nExpected: %s\nActual  : %s", exp, act)
+	}
+	if exp := []string{
+		" render.outer",
+		"In *render.inner",
+		"In render.inner",
+		"In.A int",
+		"List []string",
+		"List[0] string",
+		"List[1] string",
+		"List[2] string",
+		`M map[string]bool`,
+		`M."k" bool`,
+	}; !reflect.DeepEqual(nodes, exp) {
+		t.Errorf("unexpected nodes:\nExpected: %q\nActual  : %q", exp, nodes)
+	}
+
+	nodes = nil
+	opts.MaxSliceLen = 1
+	RenderWith(v.List, opts)
+	if exp := []string{" []string", "[0] string", "[1] string", "[2] string"}; !reflect.DeepEqual(nodes, exp) {
+		t.Errorf("unexpected nodes for truncated slice:\nExpected: %q\nActual  : %q", exp, nodes)
+	}
+}
+
+func TestRenderShortAnonStructs(t *testing.T) {
+	v := []struct {
+		a, b int
+		s    struct{ c string }
+	}{{1, 2, struct{ c string }{"x"}}}
+	opts := RenderOptions{ShortAnonStructs: true}
+
+	assertRendersLike(t, "Verbose", v,
+		`[]struct { a int; b int; s struct { c string } }{{1, 2, {"x"}}}`)
+	assertRendersWithLike(t, "Abbreviated", v, opts,
+		`[]struct{...}{{a:1, b:2, s:struct{...}{c:"x"}}}`)
+	assertRendersWithLike(t, "Map", map[string]struct{ a int }{"k": {1}}, opts,
+		`map[string]struct{...}{"k":{a:1}}`)
+	assertRendersWithLike(t, "Pointer", &struct{ a int }{1}, opts,
+		`(*struct{...}){a:1}`)
+	assertRendersWithLike(t, "Field types", struct{ T time.Month }{1}, opts,
+		`struct{...}{T:time.Month(January)}`)
+}
+
+func TestRenderMaxKeyLen(t *testing.T) {
+	type key struct {
+		ID   int
+		Name string
+	}
+	m := map[key]int{
+		{2, "a long name that is cut off"}: 2,
+		{1, "another long name"}:           1,
+		{3, "é"}:                           3,
+	}
+
+	assertRendersWithLike(t, "Struct keys", m, RenderOptions{MaxKeyLen: 20},
+		`map[render.key]int{render.key{ID:1, Nam...:1, render.key{ID:2, Nam...:2, render.key{ID:3, Nam...:3}`)
+	assertRendersWithLike(t, "Rune boundary", map[string]int{"aé": 1, "b": 2}, RenderOptions{MaxKeyLen: 3},
+		`map[string]int{"a...:1, "b":2}`)
+	assertRendersWithLike(t, "Color", map[string]int{"abcdef": 1}, RenderOptions{MaxKeyLen: 7, Color: true},
+		"\x1b[36mmap[string]int\x1b[0m{\x1b[32m\"a\x1b[0m...:1}")
+}
+
+func TestRenderTokens(t *testing.T) {
+	type flags struct {
+		On  bool
+		Ptr *int
+		Any any
+	}
+	opts := RenderOptions{TrueToken: "True", FalseToken: "False", NilToken: "Null"}
+
+	assertRendersWithLike(t, "Fields", flags{On: true}, opts,
+		`render.flags{On:True, Ptr:(*int)(Null), Any:any(Null)}`)
+	assertRendersWithLike(t, "Map keys", map[bool]struct{}{true: {}, false: {}}, opts,
+		`map[bool]struct {}{False:{}, True:{}}`)
+	assertRendersWithLike(t, "Nil", nil, opts, `Null`)
+	assertRendersWithLike(t, "Nil slice", []bool(nil), opts, `[]bool(Null)`)
+	assertRendersWithLike(t, "Compact", flags{}, RenderOptions{OmitTypePrefix: true, NilToken: "None"},
+		`{On:false, Ptr:None, Any:None}`)
+	assertRendersLike(t, "Defaults", flags{}, `render.flags{On:false, Ptr:(*int)(nil), Any:any(nil)}`)
+}
+
+func TestRenderFullPkgPath(t *testing.T) {
+	v := url.Values{"a": {"b"}}
+	opts := RenderOptions{FullPkgPath: true}
+
+	assertRendersLike(t, "Package name", v, `url.Values{"a":[]string{"b"}}`)
+	assertRendersWithLike(t, "Import path", v, opts, `net/url.Values{"a":[]string{"b"}}`)
+	assertRendersWithLike(t, "Pointer", &url.Userinfo{}, opts, `(*net/url.Userinfo){username:"", password:"", passwordSet:false}`)
+	assertRendersWithLike(t, "Composite", []url.Values{nil}, opts, `[]net/url.Values{net/url.Values(nil)}`)
+	assertRendersWithLike(t, "Builtin", []int{1}, opts, `[]int{1}`)
+	assertRendersWithLike(t, "Error", errors.New("x"), opts, `(*errors.errorString){s:"x"}`)
+
+	short := RenderOptions{ShortPkgNames: true}
+	assertRendersWithLike(t, "Short", v, short, `url.Values{"a":[]string{"b"}}`)
+	assertRendersWithLike(t, "Short composite", []*url.URL{nil}, short, `[]*url.URL{(*url.URL)(nil)}`)
+	assertRendersWithLike(t, "Full takes precedence", v, RenderOptions{ShortPkgNames: true, FullPkgPath: true},
+		`net/url.Values{"a":[]string{"b"}}`)
+}
+
+func TestRenderChannelCycles(t *testing.T) {
+	type holder struct {
+		C chan *holder
+		M map[chan *holder]*holder
+	}
+	h := &holder{C: make(chan *holder, 1), M: map[chan *holder]*holder{}}
+	h.C <- h
+	h.M[h.C] = h
+
+	// Channels are rendered as their address, without receiving from them,
+	// so cycles through them end there.
+	assertRendersLike(t, "Channel cycle", h,
+		`(*render.holder){C:(chan *render.holder)(PTR), M:map[(chan *render.holder)]*render.holder{(chan *render.holder)(PTR):<REC()>}}`)
+	assertRendersLike(t, "Channel in map", map[string]any{"c": h.C, "h": h},
+		`map[string]any{"c":(chan *render.holder)(PTR), "h":(*render.holder){C:(chan *render.holder)(PTR), M:map[(chan *render.holder)]*render.holder{(chan *render.holder)(PTR):<REC()>}}}`)
+}
+
+func TestRenderShowCap(t *testing.T) {
+	s := make([]int, 3, 8)
+	copy(s, []int{1, 2, 3})
+	opts := RenderOptions{ShowCap: true}
+
+	assertRendersWithLike(t, "Spare capacity", s, opts, `[]int{1, 2, 3}(len=3,cap=8)`)
+	assertRendersWithLike(t, "Empty", s[:0], opts, `[]int{}(len=0,cap=8)`)
+	assertRendersWithLike(t, "Nil", []int(nil), opts, `[]int(nil)`)
+	assertRendersWithLike(t, "Nested", [][]int{s[:1]}, opts, `[][]int{{1}(len=1,cap=8)}(len=1,cap=1)`)
+	assertRendersWithLike(t, "Array", [2]int{1, 2}, opts, `[2]int{1, 2}`)
+	assertRendersWithLike(t, "Bytes", []byte("ab"), RenderOptions{ShowCap: true, BytesAsString: true}, `[]byte("ab")(len=2,cap=2)`)
+	assertRendersLike(t, "Without option", s, `[]int{1, 2, 3}`)
+}
+
+func TestRenderStringerInUnexportedField(t *testing.T) {
+	type wrapper struct {
+		s  plainStringer
+		p  *plainStringer
+		i  fmt.Stringer
+		Ok plainStringer
+	}
+	p := plainStringer(2)
+	v := wrapper{1, &p, plainStringer(3), 4}
+	opts := RenderOptions{Stringers: true}
+
+	// String can't be called on values read from unexported fields, so they
+	// are rendered structurally instead.
+	assertRendersWithLike(t, "Value", v, opts,
+		`render.wrapper{s:render.plainStringer(1), p:(*render.plainStringer)(2), i:render.plainStringer(3), Ok:render.plainStringer("plain#4")}`)
+	assertRendersWithLike(t, "Pointer", &v, opts,
+		`(*render.wrapper){s:render.plainStringer(1), p:(*render.plainStringer)(2), i:render.plainStringer(3), Ok:render.plainStringer("plain#4")}`)
+	assertRendersWithLike(t, "Map value", map[string]wrapper{"k": v}, opts,
+		`map[string]render.wrapper{"k":render.wrapper{s:render.plainStringer(1), p:(*render.plainStringer)(2), i:render.plainStringer(3), Ok:render.plainStringer("plain#4")}}`)
+}
+
+func TestRenderSkipProtoInternals(t *testing.T) {
+	type messageState struct{ atomicMessageInfo *int }
+	type message struct {
+		state         messageState
+		sizeCache     int32
+		unknownFields []byte
+
+		Name   string
+		state2 int
+	}
+	v := message{Name: "n", sizeCache: 4}
+
+	assertRendersWithLike(t, "Skipped", v, RenderOptions{SkipProtoInternals: true},
+		`render.message{Name:"n", state2:0}`)
+	assertRendersLike(t, "Without option", v,
+		`render.message{state:render.messageState{atomicMessageInfo:(*int)(nil)}, sizeCache:4, unknownFields:[]uint8(nil), Name:"n", state2:0}`)
+}
+
+type countdown int
+
+func (c countdown) String() string { return fmt.Sprintf("T-%d", 10-int(c)) }
+
+func TestRenderStringerKeysSortByValue(t *testing.T) {
+	m := map[countdown]bool{1: true, 2: false, 3: true}
+
+	assertRendersLike(t, "Plain", m, `map[render.countdown]bool{1:true, 2:false, 3:true}`)
+	assertRendersWithLike(t, "Stringers", m, RenderOptions{Stringers: true},
+		`map[render.countdown]bool{"T-9":true, "T-8":false, "T-7":true}`)
+	assertRendersWithLike(t, "Stringer values", map[int]countdown{2: 3, 1: 1}, RenderOptions{Stringers: true},
+		`map[int]render.countdown{1:render.countdown("T-9"), 2:render.countdown("T-7")}`)
+}
+
+func TestRenderCalendarNames(t *testing.T) {
+	assertRendersLike(t, "Month", time.March, `time.Month(March)`)
+	assertRendersLike(t, "Weekday", time.Sunday, `time.Weekday(Sunday)`)
+	assertRendersLike(t, "Slice", []time.Month{time.January, time.December}, `[]time.Month{time.Month(January), time.Month(December)}`)
+	assertRendersLike(t, "Weekdays", []time.Weekday{time.Monday, time.Saturday}, `[]time.Weekday{time.Weekday(Monday), time.Weekday(Saturday)}`)
+	assertRendersLike(t, "Out of range", []time.Month{0, 13}, `[]time.Month{time.Month(0), time.Month(13)}`)
+	assertRendersWithLike(t, "Stringers", time.May, RenderOptions{Stringers: true}, `time.Month(May)`)
+	assertRendersWithLike(t, "Enum names", time.May, RenderOptions{
+		EnumNames: map[reflect.Type]map[int64]string{reflect.TypeOf(time.May): {5: "Mai"}},
+	}, `time.Month(Mai)`)
+}
+
+func TestRenderNilPointerRuns(t *testing.T) {
+	type testStruct struct {
+		Name string
+	}
+	v := make([]*testStruct, 6)
+	v[5] = &testStruct{Name: "x"}
+
+	assertRendersWithLike(t, "Nil run", v, RenderOptions{MinRunLength: 3},
+		`[]*render.testStruct{(*render.testStruct)(nil) x5, (*render.testStruct){Name:"x"}}`)
+	assertRendersWithLike(t, "Short run", v[3:], RenderOptions{MinRunLength: 3},
+		`[]*render.testStruct{(*render.testStruct)(nil), (*render.testStruct)(nil), (*render.testStruct){Name:"x"}}`)
+}
+
+func TestRenderValue(t *testing.T) {
+	type inner struct {
+		n    int
+		list []string
+		p    *inner
+	}
+	type outer struct {
+		Public  string
+		private inner
+	}
+	v := outer{Public: "x", private: inner{n: 3, list: []string{"a"}}}
+	v.private.p = &v.private
+	rv := reflect.ValueOf(v)
+
+	for _, tc := range []struct {
+		name string
+		rv   reflect.Value
+		exp  string
+	}{
+		{"Invalid", reflect.Value{}, `<invalid>`},
+		{"Int", reflect.ValueOf(42), `42`},
+		{"String", reflect.ValueOf("hi"), `"hi"`},
+		{"Slice", reflect.ValueOf([]int{1, 2}), `[]int{1, 2}`},
+		{"Map", reflect.ValueOf(map[string]bool{"b": true, "a": false}), `map[string]bool{"a":false, "b":true}`},
+		{"Nil pointer", reflect.ValueOf((*inner)(nil)), `(*render.inner)(nil)`},
+		{"Struct", rv.Field(0), `"x"`},
+		{"Unexported field", rv.Field(1), `render.inner{n:3, list:[]string{"a"}, p:(*render.inner){n:3, list:[]string{"a"}, p:<REC(*render.inner)>}}`},
+		{"Unexported int", rv.Field(1).Field(0), `3`},
+	} {
+		if act := RenderValue(tc.rv, RenderOptions{}); act != tc.exp {
+			t.Errorf("[%s] did not match expectations:\nExpected: %s\nActual  : %s", tc.name, tc.exp, act)
+		}
+	}
+
+	if act, exp := RenderValue(reflect.ValueOf(v), RenderOptions{OmitTypePrefix: true}), RenderWith(v, RenderOptions{OmitTypePrefix: true}); act != exp {
+		t.Errorf("RenderValue doesn't match RenderWith:\nExpected: %s\nActual  : %s", exp, act)
+	}
+}
+
+func TestRenderMapKeyLess(t *testing.T) {
+	foldLess := func(a, b reflect.Value) bool {
+		if a.Kind() != reflect.String {
+			return false
+		}
+		return strings.ToLower(a.String()) < strings.ToLower(b.String())
+	}
+	opts := RenderOptions{MapKeyLess: foldLess}
+
+	m := map[string]int{"b": 1, "A": 2, "a": 3, "C": 4}
+	assertRendersLike(t, "Default", m, `map[string]int{"A":2, "C":4, "a":3, "b":1}`)
+	assertRendersWithLike(t, "Case-insensitive", m, opts, `map[string]int{"A":2, "a":3, "b":1, "C":4}`)
+	assertRendersWithLike(t, "Nested", map[int]map[string]int{2: {"b": 1, "A": 2}, 1: {"c": 3, "B": 4}}, opts,
+		`map[int]map[string]int{1:{"B":4, "c":3}, 2:{"A":2, "b":1}}`)
+}
+
+func TestRenderEmbeddedInterface(t *testing.T) {
+	type reader struct{ io.Reader }
+	type counted struct {
+		io.Reader
+		N int
+	}
+
+	assertRendersLike(t, "Nil", reader{}, `render.reader{Reader:io.Reader(nil)}`)
+	assertRendersLike(t, "Nil pointer", &reader{}, `(*render.reader){Reader:io.Reader(nil)}`)
+	assertRendersLike(t, "Concrete", counted{Reader: strings.NewReader("x"), N: 1},
+		`render.counted{Reader:(*strings.Reader){s:"x", i:0, prevRune:-1}, N:1}`)
+	assertRendersWithLike(t, "Omit zero", reader{}, RenderOptions{OmitZero: true}, `render.reader{}`)
+}
+
+func TestRenderUnquotedStrings(t *testing.T) {
+	m := map[string]string{"name": "bob", "home": "/usr/bob", "note": "two words", "": "42"}
+
+	assertRendersLike(t, "Quoted", m,
+		`map[string]string{"":"42", "home":"/usr/bob", "name":"bob", "note":"two words"}`)
+	assertRendersWithLike(t, "Unquoted keys", m, RenderOptions{UnquotedKeys: true},
+		`map[string]string{"":"42", home:"/usr/bob", name:"bob", note:"two words"}`)
+	assertRendersWithLike(t, "Unquoted values", m, RenderOptions{UnquotedValues: true},
+		`map[string]string{"":"42", "home":/usr/bob, "name":bob, "note":"two words"}`)
+	assertRendersWithLike(t, "Unquoted both", m, RenderOptions{UnquotedKeys: true, UnquotedValues: true},
+		`map[string]string{"":"42", home:/usr/bob, name:bob, note:"two words"}`)
+	assertRendersWithLike(t, "Ambiguous", []string{"nil", "true", "1e3", "a:b", "é"}, RenderOptions{UnquotedValues: true},
+		`[]string{"nil", "true", "1e3", "a:b", é}`)
+}
+
+func TestRenderPointerToMap(t *testing.T) {
+	type item struct{ N int }
+	type holder struct {
+		A *map[string][]int
+		B *map[int]*item
+		C *map[string]int
+	}
+	a := map[string][]int{"b": {2, 1}, "a": nil}
+	b := map[int]*item{2: {N: 2}, 1: {N: 1}, 3: nil}
+
+	assertRendersLike(t, "Map of slices", &a, `(*map[string][]int){"a":nil, "b":{2, 1}}`)
+	assertRendersLike(t, "Map of pointers", &b,
+		`(*map[int]*render.item){1:(*render.item){N:1}, 2:(*render.item){N:2}, 3:(*render.item)(nil)}`)
+	assertRendersLike(t, "Fields", holder{A: &a, B: &b},
+		`render.holder{A:(*map[string][]int){"a":nil, "b":{2, 1}}, `+
+			`B:(*map[int]*render.item){1:(*render.item){N:1}, 2:(*render.item){N:2}, 3:(*render.item)(nil)}, `+
+			`C:(*map[string]int)(nil)}`)
+}
+
+func TestRenderHeader(t *testing.T) {
+	type leaf struct{ A, B int }
+	type tree struct {
+		Name   string
+		Leaves []*leaf
+	}
+	v := &tree{Name: "t", Leaves: []*leaf{{1, 2}, nil}}
+	opts := RenderOptions{Header: true}
+
+	assertRendersWithLike(t, "Tree", v, opts,
+		"// *render.tree, depth=4, nodes=9\n"+
+			`(*render.tree){Name:"t", Leaves:[]*render.leaf{(*render.leaf){A:1, B:2}, (*render.leaf)(nil)}}`)
+	assertRendersWithLike(t, "Scalar", 5, opts, "// int, depth=1, nodes=1\n5")
+	assertRendersWithLike(t, "Nil", nil, opts, "// nil, depth=1, nodes=1\nnil")
+	assertRendersLike(t, "Off", 5, "5")
+}
+
+func TestRenderAtomicValue(t *testing.T) {
+	var v atomic.Value
+	assertRendersLike(t, "Empty", v, `atomic.Value(nil)`)
+	v.Store([]string{"a"})
+	assertRendersLike(t, "Stored", v, `atomic.Value([]string{"a"})`)
+}
+
+func TestRenderCommonElemTypes(t *testing.T) {
+	type point struct{ X int }
+	opts := RenderOptions{CommonElemTypes: true}
+
+	assertRendersWithLike(t, "Homogeneous", []any{1, 2, 3}, opts, `[]any{1, 2, 3} /* all int */`)
+	assertRendersWithLike(t, "Structs", [2]any{point{1}, point{2}}, opts,
+		`[2]any{render.point{X:1}, render.point{X:2}} /* all render.point */`)
+	assertRendersWithLike(t, "Heterogeneous", []any{1, "a"}, opts, `[]any{1, "a"}`)
+	assertRendersWithLike(t, "Nil element", []any{1, nil}, opts, `[]any{1, any(nil)}`)
+	assertRendersWithLike(t, "Empty", []any{}, opts, `[]any{}`)
+	assertRendersWithLike(t, "Concrete", []int{1}, opts, `[]int{1}`)
+	assertRendersLike(t, "Off", []any{1, 2}, `[]any{1, 2}`)
+}
+
+func TestRenderCompressMapPaths(t *testing.T) {
+	opts := RenderOptions{CompressMapPaths: true}
+
+	chain := map[string]any{"a": map[string]any{"b": map[string]any{"c": 1}}}
+	assertRendersLike(t, "Uncompressed", chain,
+		`map[string]any{"a":map[string]any{"b":map[string]any{"c":1}}}`)
+	assertRendersWithLike(t, "Chain", chain, opts, `map[string]any{"a.b.c":1}`)
+
+	mixed := map[string]any{
+		"db":  map[string]any{"host": "h", "port": 5432},
+		"log": map[string]any{"level": map[string]string{"root": "info"}},
+		"x":   map[string]any{"y": map[string]int{"p": 1, "q": 2}},
+	}
+	assertRendersWithLike(t, "Mixed", mixed, opts,
+		`map[string]any{"db":map[string]any{"host":"h", "port":5432}, "log.level.root":"info", "x.y":map[string]int{"p":1, "q":2}}`)
+
+	typed := map[string]map[string][]int{"a": {"b": {1}}}
+	assertRendersWithLike(t, "Typed", typed, opts, `map[string]map[string][]int{"a.b":{1}}`)
+
+	cycle := map[string]any{}
+	cycle["self"] = cycle
+	assertRendersWithLike(t, "Cycle", cycle, opts, `map[string]any{"self":<REC(map[string]any)>}`)
+}
+
+func TestRenderBoxedNamedTypes(t *testing.T) {
+	type myStringSlice []string
+	type myStringMap map[string]string
+	type myIntType int
+
+	assertRendersLike(t, "Slice", []any{myIntType(12), myStringSlice{"a"}, myStringMap{"k": "v"}},
+		`[]any{render.myIntType(12), render.myStringSlice{"a"}, render.myStringMap{"k":"v"}}`)
+	assertRendersLike(t, "Map", map[string]any{"i": myIntType(12), "s": myStringSlice{"a"}, "m": myStringMap{"k": "v"}},
+		`map[string]any{"i":render.myIntType(12), "m":render.myStringMap{"k":"v"}, "s":render.myStringSlice{"a"}}`)
+	assertRendersLike(t, "Nil", []any{myStringSlice(nil), myStringMap(nil)},
+		`[]any{render.myStringSlice(nil), render.myStringMap(nil)}`)
+	assertRendersWithLike(t, "Elided", []any{myIntType(12), myStringSlice{"a"}}, RenderOptions{ElideElemTypes: true},
+		`[]any{render.myIntType(12), render.myStringSlice{"a"}}`)
+}
+
+type ptrStringerError struct{ code int }
+
+func (e *ptrStringerError) Error() string { return fmt.Sprintf("error %d", e.code) }
+func (e ptrStringerError) String() string { return fmt.Sprintf("string %d", e.code) }
+
+func TestRenderErrorStringerPrecedence(t *testing.T) {
+	type holder struct {
+		Value ptrStringerError
+		List  []stringerError
+		Map   map[string]stringerError
+	}
+	opts := RenderOptions{Errors: true, Stringers: true}
+
+	assertRendersWithLike(t, "Value receivers", holder{
+		Value: ptrStringerError{1},
+		List:  []stringerError{{}},
+		Map:   map[string]stringerError{"k": {}},
+	}, opts, `render.holder{Value:render.ptrStringerError("error 1"), List:[]render.stringerError{render.stringerError("error text")}, `+
+		`Map:map[string]render.stringerError{"k":render.stringerError("error text")}}`)
+	assertRendersWithLike(t, "Pointer receiver in map", map[string]ptrStringerError{"k": {2}}, opts,
+		`map[string]render.ptrStringerError{"k":render.ptrStringerError("error 2")}`)
+	assertRendersWithLike(t, "Pointer receiver in slice", []ptrStringerError{{3}}, opts,
+		`[]render.ptrStringerError{render.ptrStringerError("error 3")}`)
+	assertRendersWithLike(t, "Stringers only", map[string]ptrStringerError{"k": {4}}, RenderOptions{Stringers: true},
+		`map[string]render.ptrStringerError{"k":render.ptrStringerError("string 4")}`)
+}
+
+func TestRenderMaxDepth(t *testing.T) {
+	type node struct {
+		Name string
+		Kids []*node
+	}
+	tree := &node{Name: "a", Kids: []*node{{Name: "b", Kids: []*node{{Name: "c"}}}}}
+
+	assertRendersWithLike(t, "Depth 1", tree, RenderOptions{MaxDepth: 1},
+		`(*render.node){Name:"a", Kids:[]*render.node{...}}`)
+	assertRendersWithLike(t, "Depth 2", tree, RenderOptions{MaxDepth: 2},
+		`(*render.node){Name:"a", Kids:[]*render.node{(*render.node){...}}}`)
+	assertRendersWithLike(t, "Nil and empty", &node{Kids: []*node{}}, RenderOptions{MaxDepth: 1},
+		`(*render.node){Name:"", Kids:[]*render.node{...}}`)
+	assertRendersWithLike(t, "Nil", node{}, RenderOptions{MaxDepth: 1}, `render.node{Name:"", Kids:[]*render.node(nil)}`)
+}
+
+func TestRenderMaxStringLen(t *testing.T) {
+	opts := RenderOptions{MaxStringLen: 3}
+
+	assertRendersWithLike(t, "Cut", "abcdef", opts, `"abc"...`)
+	assertRendersWithLike(t, "Short", []string{"ab", "abc"}, opts, `[]string{"ab", "abc"}`)
+	assertRendersWithLike(t, "Runes", "héllo", opts, `"hél"...`)
+	assertRendersWithLike(t, "Named", myString("abcd"), opts, `render.myString("abc"...)`)
+}
+
+type myString string
+
+func TestRenderSafe(t *testing.T) {
+	type secret struct {
+		User     string
+		Password string `render:"redact"`
+	}
+	type node struct {
+		Next *node
+		Data []int
+		Text string
+	}
+	var deep *node
+	for i := 0; i < 1000; i++ {
+		deep = &node{Next: deep, Data: make([]int, 1000), Text: strings.Repeat("x", 10000)}
+	}
+
+	out := RenderSafe(deep)
+	if opts := SafeRenderOptions(); len(out) > opts.MaxTotalBytes+len("...<truncated>") {
+		t.Errorf("RenderSafe output is %d bytes long, more than %d", len(out), opts.MaxTotalBytes)
+	}
+	for _, marker := range []string{`"...`, "...(+900 more of 1000)", "{...}"} {
+		if !strings.Contains(out, marker) {
+			t.Errorf("RenderSafe output doesn't contain %q", marker)
+		}
+	}
+
+	if out := RenderWith([]any{panickyStringer{}}, RenderOptions{Stringers: true}); out != `[]any{<PANIC: boom>}` {
+		t.Errorf("Unexpected output: %s", out)
+	}
+	if out := RenderSafe(secret{"bob", "hunter2"}); out != `render.secret{User:"bob", Password:string(<redacted>)}` {
+		t.Errorf("RenderSafe doesn't redact: %s", out)
+	}
+}
+
+func TestRenderPointerKeyIDs(t *testing.T) {
+	vals := make([]int, 3)
+	vals[0], vals[1], vals[2] = 7, 5, 5
+	m := map[*int]string{&vals[2]: "c", &vals[0]: "a", &vals[1]: "b", nil: "n"}
+	opts := RenderOptions{PointerKeyIDs: true}
+
+	exp := `map[*int]string{(*int)(nil):"n", (*int @#1)(7):"a", (*int @#2)(5):"b", (*int @#3)(5):"c"}`
+	for i := 0; i < 5; i++ {
+		assertRendersWithLike(t, "Keys", m, opts, exp)
+	}
+	assertRendersLike(t, "Off", m, `map[*int]string{(*int)(nil):"n", (*int)(7):"a", (*int)(5):"b", (*int)(5):"c"}`)
+
+	type item struct{ P *int }
+	assertRendersWithLike(t, "Struct keys", map[*item]int{{P: &vals[0]}: 1}, opts,
+		`map[*render.item]int{(*render.item @#1){P:(*int)(7)}:1}`)
+	assertRendersWithLike(t, "Values", map[int]*int{1: &vals[0]}, opts, `map[int]*int{1:(*int)(7)}`)
+}
+
+func TestRenderDefaults(t *testing.T) {
+	type limits struct{ Min, Max int }
+	type config struct {
+		Host    string
+		Port    int
+		Tags    []string
+		Limits  limits
+		retries int
+	}
+	defaults := config{Host: "localhost", Port: 80, Tags: []string{"a"}, retries: 3}
+
+	v := defaults
+	v.Port = 8080
+	assertRendersWithLike(t, "Override", v, RenderOptions{Defaults: defaults}, `render.config{Port:8080}`)
+	assertRendersWithLike(t, "Pointer defaults", &v, RenderOptions{Defaults: &defaults}, `(*render.config){Port:8080}`)
+
+	v.Tags, v.retries = []string{"a", "b"}, 5
+	assertRendersWithLike(t, "Deep", v, RenderOptions{Defaults: defaults}, `render.config{Port:8080, Tags:[]string{"a", "b"}, retries:5}`)
+	assertRendersWithLike(t, "Same", defaults, RenderOptions{Defaults: defaults}, `render.config{}`)
+	assertRendersWithLike(t, "Other types", []limits{{1, 2}}, RenderOptions{Defaults: defaults}, `[]render.limits{render.limits{Min:1, Max:2}}`)
+	assertRendersWithLike(t, "Nested defaults", struct{ L limits }{limits{0, 9}}, RenderOptions{Defaults: limits{}},
+		`struct { L render.limits }{L:render.limits{Max:9}}`)
+}
+
+func TestRenderFuncValues(t *testing.T) {
+	type handlers struct {
+		OnStart func()
+		OnStop  func()
+	}
+	f := func() {}
+
+	m := map[string]func(){"b": f, "a": nil, "c": f}
+	for i := 0; i < 5; i++ {
+		assertRendersLike(t, "Map", m, `map[string](func()){"a":(func())(nil), "b":(func())(PTR), "c":(func())(PTR)}`)
+	}
+	assertRendersLike(t, "Slice", []func(){f, nil}, `[](func()){(func())(PTR), (func())(nil)}`)
+	assertRendersLike(t, "Fields", handlers{OnStart: f}, `render.handlers{OnStart:(func())(PTR), OnStop:(func())(nil)}`)
+	assertRendersLike(t, "Interface", []any{f}, `[]any{(func())(PTR)}`)
+}
+
+func TestRenderTypePrefixKinds(t *testing.T) {
+	type level int
+	type entry struct {
+		Level level
+		Tags  []string
+		Attrs map[string]any
+		Next  *entry
+	}
+	v := entry{Level: 3, Tags: []string{"a"}, Attrs: map[string]any{"k": level(1)}, Next: &entry{}}
+
+	assertRendersWithLike(t, "Structs only", v, RenderOptions{TypePrefixKinds: map[reflect.Kind]bool{reflect.Struct: true}},
+		`render.entry{Level:3, Tags:{"a"}, Attrs:{"k":1}, Next:(*render.entry){Level:0, Tags:nil, Attrs:nil, Next:nil}}`)
+	assertRendersWithLike(t, "Ints only", v, RenderOptions{TypePrefixKinds: map[reflect.Kind]bool{reflect.Int: true}},
+		`{Level:render.level(3), Tags:{"a"}, Attrs:{"k":render.level(1)}, Next:{Level:render.level(0), Tags:nil, Attrs:nil, Next:nil}}`)
+	assertRendersWithLike(t, "Empty set", level(2), RenderOptions{TypePrefixKinds: map[reflect.Kind]bool{}}, `2`)
+	assertRendersLike(t, "Unset", level(2), `render.level(2)`)
+}
+
+func TestRenderNumericStringKeys(t *testing.T) {
+	type id string
+	opts := RenderOptions{NumericStringKeys: true}
+	numeric := map[string]int{"1": 1, "10": 10, "2": 2, "-3": -3, "2.5": 0}
+
+	assertRendersLike(t, "Lexical", numeric, `map[string]int{"-3":-3, "1":1, "10":10, "2":2, "2.5":0}`)
+	assertRendersWithLike(t, "Numeric", numeric, opts, `map[string]int{"-3":-3, "1":1, "2":2, "2.5":0, "10":10}`)
+	assertRendersWithLike(t, "Mixed", map[string]int{"1": 1, "10": 10, "2": 2, "x": 0}, opts,
+		`map[string]int{"1":1, "10":10, "2":2, "x":0}`)
+	assertRendersWithLike(t, "Named", map[id]bool{"10": true, "9": false}, opts, `map[render.id]bool{"9":false, "10":true}`)
+	assertRendersWithLike(t, "Not decimal", map[string]int{"Inf": 1, "1": 2}, opts, `map[string]int{"1":2, "Inf":1}`)
+}
+
+type celsius struct{ v float64 }
+
+type userID struct{ ID string }
+
+func TestRenderInlineWrappers(t *testing.T) {
+	type reading struct {
+		Temp celsius
+		By   userID
+		At   *celsius
+	}
+	type point struct{ X, Y int }
+	opts := RenderOptions{InlineWrappers: true}
+
+	assertRendersWithLike(t, "Unexported field", celsius{21.5}, opts, `render.celsius(21.5)`)
+	assertRendersWithLike(t, "Exported field", userID{"u1"}, opts, `render.userID("u1")`)
+	assertRendersWithLike(t, "Fields", reading{Temp: celsius{3}, By: userID{"u2"}, At: &celsius{4}}, opts,
+		`render.reading{Temp:render.celsius(3), By:render.userID("u2"), At:(*render.celsius)(4)}`)
+	assertRendersWithLike(t, "Two fields", point{1, 2}, opts, `render.point{X:1, Y:2}`)
+	assertRendersWithLike(t, "Anonymous", struct{ A int }{1}, opts, `struct { A int }{1}`)
+	assertRendersLike(t, "Off", celsius{21.5}, `render.celsius{v:21.5}`)
+
+	type token struct {
+		Key string `render:"redact"`
+	}
+	safe := SafeRenderOptions()
+	safe.InlineWrappers = true
+	assertRendersWithLike(t, "Redacted", token{"hunter2"}, safe, `render.token{Key:string(<redacted>)}`)
+	assertRendersWithLike(t, "Omitted", userID{}, RenderOptions{InlineWrappers: true, OmitZero: true}, `render.userID{}`)
+	assertRendersWithLike(t, "Filtered", userID{"u3"}, RenderOptions{InlineWrappers: true, IncludeFields: []string{"Other"}},
+		`render.userID{...}`)
+}
+
+func TestRenderNestedArrayTypes(t *testing.T) {
+	type testStruct struct{ A int }
+	type grid [2][3]int
+
+	assertRendersLike(t, "Two dimensions", [2][3]int{{1, 2, 3}, {4, 5, 6}}, `[2][3]int{{1, 2, 3}, {4, 5, 6}}`)
+	assertRendersLike(t, "Structs", [2]testStruct{{1}, {2}}, `[2]render.testStruct{render.testStruct{A:1}, render.testStruct{A:2}}`)
+	assertRendersLike(t, "Slice of arrays", [][2]*testStruct{{{1}, nil}},
+		`[][2]*render.testStruct{{(*render.testStruct){A:1}, (*render.testStruct)(nil)}}`)
+	assertRendersLike(t, "Named", grid{}, `render.grid{[3]int{0, 0, 0}, [3]int{0, 0, 0}}`)
+	assertRendersLike(t, "Mixed", [1][]map[string][2]int{{{"a": {1, 2}}}}, `[1][]map[string][2]int{{{"a":{1, 2}}}}`)
+	assertRendersLike(t, "Map", map[[2]int][1][2]string{{1, 2}: {{"a", "b"}}}, `map[[2]int][1][2]string{[2]int{1, 2}:{{"a", "b"}}}`)
+}
+
+func TestRenderShowNumericKinds(t *testing.T) {
+	type sample struct {
+		I   int
+		I64 int64
+		B   byte
+		U32 uint32
+		F32 float32
+		F64 float64
+		C   complex128
+		S   string
+		OK  bool
+	}
+	opts := RenderOptions{ShowNumericKinds: true}
+
+	assertRendersWithLike(t, "Fields", sample{1, 2, 3, 4, 0.5, 1, 1i, "s", true}, opts,
+		`render.sample{I:int(1), I64:int64(2), B:uint8(3), U32:uint32(4), F32:float32(0.5), F64:float64(1), `+
+			`C:complex128(0+1i), S:"s", OK:true}`)
+	assertRendersWithLike(t, "Top level", uint32(1337), opts, `uint32(1337)`)
+	assertRendersWithLike(t, "Interfaces", []any{42, int8(42), 4.2}, opts, `[]any{int(42), int8(42), float64(4.2)}`)
+	assertRendersWithLike(t, "Implied", []int64{42}, opts, `[]int64{42}`)
+	assertRendersWithLike(t, "Map", map[uint16]float32{1: 2}, opts, `map[uint16]float32{1:2}`)
+	assertRendersLike(t, "Off", uint32(1337), `1337`)
+}
+
+type colorCode struct{ r, g, b uint8 }
+
+func (c colorCode) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("#%02x%02x%02x", c.r, c.g, c.b)), nil
+}
+
+type badMarshaler struct{ N int }
+
+func (*badMarshaler) MarshalText() ([]byte, error) { return nil, errors.New("bad") }
+
+func TestRenderTextMarshaler(t *testing.T) {
+	type palette struct {
+		Main   colorCode
+		Accent *colorCode
+		None   *colorCode
+		Bad    badMarshaler
+	}
+	opts := RenderOptions{UseTextMarshaler: true}
+
+	assertRendersWithLike(t, "Value", colorCode{255, 0, 16}, opts, `render.colorCode(#ff0010)`)
+	assertRendersWithLike(t, "Fields", palette{Main: colorCode{1, 2, 3}, Accent: &colorCode{}, Bad: badMarshaler{4}}, opts,
+		`render.palette{Main:render.colorCode(#010203), Accent:(*render.colorCode)(#000000), None:(*render.colorCode)(nil), Bad:render.badMarshaler{N:4}}`)
+	assertRendersWithLike(t, "Error", &badMarshaler{5}, opts, `(*render.badMarshaler){N:5}`)
+	assertRendersWithLike(t, "Stdlib", netip.MustParsePrefix("10.0.0.0/8"), opts, `netip.Prefix(10.0.0.0/8)`)
+	assertRendersLike(t, "Off", colorCode{1, 2, 3}, `render.colorCode{r:1, g:2, b:3}`)
+}
+
+func TestRenderMaxFields(t *testing.T) {
+	type wide struct {
+		F0, F1, F2, F3, F4, F5, F6, F7, F8, F9 int
+		A, B                                   string
+	}
+	v := wide{F0: 0, F1: 1, F2: 2, A: "a"}
+
+	assertRendersWithLike(t, "Limited", v, RenderOptions{MaxFields: 3}, `render.wide{F0:0, F1:1, F2:2, ...(+9 more fields)}`)
+	assertRendersWithLike(t, "Sorted", v, RenderOptions{MaxFields: 2, SortStructFields: true}, `render.wide{A:"a", B:"", ...(+10 more fields)}`)
+	assertRendersWithLike(t, "Omit zero", v, RenderOptions{MaxFields: 1, OmitZero: true}, `render.wide{F1:1, ...(+2 more fields)}`)
+	assertRendersWithLike(t, "Enough", struct{ A, B int }{1, 2}, RenderOptions{MaxFields: 2}, `struct { A int; B int }{1, 2}`)
+	assertRendersWithLike(t, "Nested", []wide{v}, RenderOptions{MaxFields: 1}, `[]render.wide{render.wide{F0:0, ...(+11 more fields)}}`)
+}
+
+func TestRenderRecursionThroughInterfaces(t *testing.T) {
+	type node struct {
+		Name string
+		Next any
+	}
+	direct := &node{Name: "a"}
+	direct.Next = direct
+	viaSlice := &node{Name: "b"}
+	viaSlice.Next = []any{viaSlice}
+	viaMap := &node{Name: "c"}
+	viaMap.Next = map[string]any{"up": &viaMap}
+	self := []any{nil}
+	self[0] = self
+
+	assertRendersLike(t, "Direct", direct, `(*render.node){Name:"a", Next:<REC(*render.node)>}`)
+	assertRendersLike(t, "Via slice", viaSlice, `(*render.node){Name:"b", Next:[]any{<REC(*render.node)>}}`)
+	assertRendersLike(t, "Via map", viaMap, `(*render.node){Name:"c", Next:map[string]any{"up":<REC(**render.node)>}}`)
+	assertRendersLike(t, "Slice", self, `[]any{<REC([]any)>}`)
+
+	var x any
+	x = &x
+	assertRendersLike(t, "Pointer to itself", x, `(*any)(<REC(*any)>)`)
+}
+
+func TestRenderPathComments(t *testing.T) {
+	type addr struct{ City string }
+	type user struct {
+		Name  string
+		Addr  addr
+		Tags  []string
+		Attrs map[string]int
+	}
+	v := user{Name: "foo", Addr: addr{"x"}, Tags: []string{"a"}, Attrs: map[string]int{"k": 1}}
+	opts := RenderOptions{Indent: "  ", PathComments: true}
+
+	assertRendersWithLike(t, "Nested", v, opts, strings.Join([]string{
+		`render.user{`,
+		`  Name:"foo", // .Name`,
+		`  Addr:render.addr{`,
+		`    City:"x", // .Addr.City`,
+		`  }, // .Addr`,
+		`  Tags:[]string{`,
+		`    "a", // .Tags[0]`,
+		`  }, // .Tags`,
+		`  Attrs:map[string]int{`,
+		`    "k":1, // .Attrs."k"`,
+		`  }, // .Attrs`,
+		`}`,
+	}, "\n"))
+	assertRendersWithLike(t, "Not indented", v, RenderOptions{PathComments: true},
+		`render.user{Name:"foo", Addr:render.addr{City:"x"}, Tags:[]string{"a"}, Attrs:map[string]int{"k":1}}`)
+}
+
+func TestRenderBoolAsInt(t *testing.T) {
+	type flags struct {
+		On, Off bool
+		Named   myBool
+	}
+	opts := RenderOptions{BoolAsInt: true}
+
+	assertRendersWithLike(t, "Fields", flags{On: true, Named: true}, opts, `render.flags{On:1, Off:0, Named:render.myBool(1)}`)
+	assertRendersWithLike(t, "Slice", []bool{true, false}, opts, `[]bool{1, 0}`)
+	assertRendersWithLike(t, "Map keys", map[bool]struct{}{true: {}, false: {}}, opts, `map[bool]struct {}{0:{}, 1:{}}`)
+	assertRendersWithLike(t, "Tokens", []bool{true, false}, RenderOptions{BoolAsInt: true, TrueToken: "yes"}, `[]bool{yes, 0}`)
+	assertRendersLike(t, "Off", []bool{true, false}, `[]bool{true, false}`)
+}
+
+type myBool bool
+
+func TestRenderFileModes(t *testing.T) {
+	type entry struct {
+		Name string
+		Mode os.FileMode
+	}
+
+	assertRendersLike(t, "Directory", os.ModeDir|0o755, `fs.FileMode(drwxr-xr-x)`)
+	assertRendersLike(t, "Regular", fs.FileMode(0o644), `fs.FileMode(-rw-r--r--)`)
+	assertRendersLike(t, "Special bits", os.ModeSetuid|os.ModeSticky|0o4755&0o777, `fs.FileMode(utrwxr-xr-x)`)
+	assertRendersLike(t, "Field", entry{"x", 0o600}, `render.entry{Name:"x", Mode:fs.FileMode(-rw-------)}`)
+	assertRendersWithLike(t, "Stringers", fs.FileMode(0o644), RenderOptions{Stringers: true}, `fs.FileMode(-rw-r--r--)`)
+}
+
+func TestRenderIndentedEmptyContainers(t *testing.T) {
+	type empty struct{}
+	type holder struct {
+		S []int
+		M map[string]int
+		E empty
+		A [0]int
+	}
+	opts := RenderOptions{Indent: "  "}
+
+	assertRendersWithLike(t, "Slice", []int{}, opts, `[]int{}`)
+	assertRendersWithLike(t, "Map", map[string]int{}, opts, `map[string]int{}`)
+	assertRendersWithLike(t, "Struct", struct{}{}, opts, `struct {}{}`)
+	assertRendersWithLike(t, "Fields", holder{S: []int{}, M: map[string]int{}}, opts, strings.Join([]string{
+		`render.holder{`,
+		`  S:[]int{},`,
+		`  M:map[string]int{},`,
+		`  E:render.empty{},`,
+		`  A:[0]int{},`,
+		`}`,
+	}, "\n"))
+	assertRendersWithLike(t, "All omitted", holder{}, RenderOptions{Indent: "  ", OmitZero: true}, `render.holder{}`)
+}
+
+type greeter struct{ name string }
+
+func (g greeter) Greet() string { return "hi " + g.name }
+
+func (g *greeter) Rename(name string) { g.name = name }
+
+func plainFunc() {}
+
+func TestRenderFuncNames(t *testing.T) {
+	type callbacks struct {
+		Greet  func() string
+		Rename func(string)
+		Plain  func()
+		None   func()
+	}
+	g := &greeter{"bob"}
+	v := callbacks{Greet: g.Greet, Rename: g.Rename, Plain: plainFunc}
+
+	assertRendersWithLike(t, "Names", v, RenderOptions{FuncNames: true},
+		`render.callbacks{Greet:(func() string)(methodValue(render.greeter.Greet)), `+
+			`Rename:(func(string))(methodValue(render.(*greeter).Rename)), Plain:(func())(render.plainFunc), None:(func())(nil)}`)
+	assertRendersWithLike(t, "Method expression", greeter.Greet, RenderOptions{FuncNames: true}, `(func(render.greeter) string)(render.greeter.Greet)`)
+	assertRendersLike(t, "Off", v,
+		`render.callbacks{Greet:(func() string)(PTR), Rename:(func(string))(PTR), Plain:(func())(PTR), None:(func())(nil)}`)
+}
+
+func TestRenderWrapAt(t *testing.T) {
+	type labels struct {
+		Name string
+		Tags []string
+		IDs  []int
+	}
+	v := labels{"x", []string{"alpha", "beta", "gamma", "delta"}, []int{1, 2}}
+
+	assertRendersWithLike(t, "Wrapped", v, RenderOptions{Indent: "  ", WrapAt: 40}, strings.Join([]string{
+		`render.labels{`,
+		`  Name:"x",`,
+		`  Tags:[]string{`,
+		`    "alpha",`,
+		`    "beta",`,
+		`    "gamma",`,
+		`    "delta",`,
+		`  },`,
+		`  IDs:[]int{1, 2},`,
+		`}`,
+	}, "\n"))
+	assertRendersWithLike(t, "Fits", v, RenderOptions{Indent: "  ", WrapAt: 100},
+		`render.labels{Name:"x", Tags:[]string{"alpha", "beta", "gamma", "delta"}, IDs:[]int{1, 2}}`)
+
+	var paths []string
+	onNode := func(path string, _ reflect.Value) { paths = append(paths, path) }
+	RenderWith(v, RenderOptions{Indent: "  ", WrapAt: 40, OnNode: onNode})
+	if len(paths) != 10 {
+		t.Errorf("OnNode called for %q, want each node once", paths)
+	}
+}
+
+func TestRenderWrapAtPanic(t *testing.T) {
+	type labels struct {
+		X panickyStringer
+		Y []string
+		Z []int
+	}
+	v := labels{Y: []string{"alpha", "beta", "gamma"}, Z: []int{1, 2, 3, 4, 5, 6}}
+
+	// A panic while trying a value on one line doesn't keep its siblings there.
+	assertRendersWithLike(t, "Panic", v, RenderOptions{Indent: "  ", WrapAt: 20, Stringers: true}, strings.Join([]string{
+		`render.labels{`,
+		`  X:<PANIC: boom>,`,
+		`  Y:[]string{`,
+		`    "alpha",`,
+		`    "beta",`,
+		`    "gamma",`,
+		`  },`,
+		`  Z:[]int{`,
+		`    1,`,
+		`    2,`,
+		`    3,`,
+		`    4,`,
+		`    5,`,
+		`    6,`,
+		`  },`,
+		`}`,
+	}, "\n"))
+}
+
+func TestRenderArrayKeyOrder(t *testing.T) {
+	m := map[[2]int]string{{10, 1}: "d", {9, 5}: "c", {1, 10}: "b", {1, 2}: "a", {-1, 0}: "z"}
+	exp := `map[[2]int]string{[2]int{-1, 0}:"z", [2]int{1, 2}:"a", [2]int{1, 10}:"b", [2]int{9, 5}:"c", [2]int{10, 1}:"d"}`
+	for i := 0; i < 10; i++ {
+		assertRendersLike(t, "Array keys", m, exp)
+	}
+}
+
+func TestRenderLocker(t *testing.T) {
+	type shared struct {
+		N     int
+		Names []string
+	}
+	var mu sync.Mutex
+	v := &shared{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				mu.Lock()
+				v.Names = append(v.Names, "x")
+				v.N = len(v.Names)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	pattern := regexp.MustCompile(`^\(\*render\.shared\)\{N:(\d+), Names:(.*)\}$`)
+	for i := 0; i < 50; i++ {
+		out := RenderWith(v, RenderOptions{Locker: &mu})
+		m := pattern.FindStringSubmatch(out)
+		if m == nil {
+			t.Fatalf("unexpected rendering: %s", out)
+		}
+		if n := strings.Count(m[2], `"x"`); strconv.Itoa(n) != m[1] {
+			t.Fatalf("inconsistent rendering, N:%s with %d names", m[1], n)
+		}
+	}
+	wg.Wait()
+}
+
+func TestRenderStringEscaper(t *testing.T) {
+	opts := RenderOptions{StringEscaper: func(s string) string { return strings.ReplaceAll(s, "\n", "<br>") }}
+
+	assertRendersWithLike(t, "Value", "a\nb\t", opts, `"a<br>b\t"`)
+	identity := RenderOptions{StringEscaper: func(s string) string { return s }}
+	assertRendersWithLike(t, "Quotes and backslashes", map[string]string{`a", "b`: `c\d`}, identity,
+		`map[string]string{"a\", \"b":"c\\d"}`)
+	assertRendersWithLike(t, "Control characters", "a\x00\n", identity, `"a\x00\n"`)
+	assertRendersWithLike(t, "Map", map[string]string{"k\n1": "v\n1"}, opts, `map[string]string{"k<br>1":"v<br>1"}`)
+	assertRendersWithLike(t, "Unquoted keys", map[string]int{"k": 1, "a\nb": 2},
+		RenderOptions{StringEscaper: opts.StringEscaper, UnquotedKeys: true}, `map[string]int{"a<br>b":2, k:1}`)
+	assertRendersLike(t, "Default", "a\nb", `"a\nb"`)
+}
+
+type privateKey struct{ d []byte }
+
+func TestRenderOpaqueTypes(t *testing.T) {
+	type holder struct {
+		Key  privateKey
+		Ptr  *privateKey
+		Keys []privateKey
+		ByID map[int]privateKey
+	}
+	k := privateKey{[]byte("secret")}
+	v := holder{Key: k, Ptr: &k, Keys: []privateKey{k}, ByID: map[int]privateKey{1: k}}
+	opts := RenderOptions{OpaqueTypes: map[reflect.Type]string{reflect.TypeOf(k): "<private key>"}}
+
+	assertRendersWithLike(t, "Opaque", v, opts,
+		`render.holder{Key:render.privateKey(<private key>), Ptr:(*render.privateKey)(<private key>), `+
+			`Keys:[]render.privateKey{render.privateKey(<private key>)}, ByID:map[int]render.privateKey{1:render.privateKey(<private key>)}}`)
+	assertRendersWithLike(t, "Nil pointer", holder{}, opts,
+		`render.holder{Key:render.privateKey(<private key>), Ptr:(*render.privateKey)(nil), Keys:[]render.privateKey(nil), ByID:map[int]render.privateKey(nil)}`)
+}
+
+func TestRenderRecursionKinds(t *testing.T) {
+	m := map[string]any{}
+	v := struct{ M map[string]any }{m}
+	m["self"] = m
+
+	assertRendersWithLike(t, "Map", v, RenderOptions{RecursionKinds: true},
+		`struct { M map[string]interface {} }{{"self":<REC(map, map[string]any)>}}`)
+	assertRendersLike(t, "Default", v, `struct { M map[string]interface {} }{{"self":<REC(map[string]any)>}}`)
+
+	type node struct{ Next *node }
+	n := &node{}
+	n.Next = n
+	assertRendersWithLike(t, "Pointer", n, RenderOptions{RecursionKinds: true}, `(*render.node){Next:<REC(ptr, *render.node)>}`)
+}
+
+func TestRenderNeedsAddress(t *testing.T) {
+	type plain struct {
+		a int
+		B string
+	}
+	type withTime struct{ t time.Time }
+	type withIP struct{ ip [1]net.IP }
+	type nested struct{ inner withTime }
+
+	for _, tc := range []struct {
+		name string
+		v    any
+		opts RenderOptions
+		exp  bool
+	}{
+		{"Plain", plain{}, RenderOptions{}, false},
+		{"Pointer", &withTime{}, RenderOptions{UnexportedTimes: true}, false},
+		{"Time", withTime{}, RenderOptions{}, false},
+		{"UnexportedTimes", withTime{}, RenderOptions{UnexportedTimes: true}, true},
+		{"Nested", nested{}, RenderOptions{UnexportedTimes: true}, true},
+		{"IP", withIP{}, RenderOptions{}, true},
+		{"Defaults", plain{}, RenderOptions{Defaults: plain{}}, true},
+		{"ShowMethods", plain{}, RenderOptions{ShowMethods: true}, true},
+	} {
+		r := renderer{opts: &tc.opts}
+		if act := r.needsAddress(reflect.ValueOf(tc.v)); act != tc.exp {
+			t.Errorf("[%s] needsAddress = %v, want %v", tc.name, act, tc.exp)
+		}
+	}
+}
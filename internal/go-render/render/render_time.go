@@ -5,22 +5,71 @@ import (
 	"time"
 )
 
-func renderTime(value reflect.Value) (string, bool) {
-	if instant, ok := convertTime(value); !ok {
+// renderTime returns the rendered form of value if it is a time.Time.
+//
+// Unexported time.Time fields can only be read when the UnexportedTimes option
+// is set and the field is addressable.
+func (r *renderer) renderTime(value reflect.Value) (string, bool) {
+	instant, ok := r.convertTime(value)
+	if !ok {
 		return "", false
-	} else if instant.IsZero() {
+	}
+	if instant.IsZero() {
 		return "0", true
-	} else {
-		return instant.String(), true
 	}
+	if r.opts.TimeUTC {
+		instant = instant.UTC()
+	}
+	if r.opts.TimeLayout != "" {
+		return instant.Format(r.opts.TimeLayout), true
+	}
+	return instant.String(), true
+}
+
+func (r *renderer) convertTime(value reflect.Value) (t time.Time, ok bool) {
+	if value.Type() != timeType {
+		return t, false
+	}
+	if !value.CanInterface() {
+		if !r.opts.UnexportedTimes {
+			return t, false
+		}
+		if value, ok = exportedValue(value); !ok {
+			return t, false
+		}
+	}
+	return value.Interface().(time.Time), true
+}
+
+// durationText returns the rendered form of value if it is a time.Duration
+// to be rendered in human-readable form (see HumanDurations).
+func (r *renderer) durationText(value reflect.Value) (string, bool) {
+	if !r.opts.HumanDurations || value.Type() != durationType {
+		return "", false
+	}
+	return time.Duration(value.Int()).String(), true
 }
 
-func convertTime(value reflect.Value) (t time.Time, ok bool) {
-	if value.Type() == timeType {
-		defer func() { recover() }()
-		t, ok = value.Interface().(time.Time)
+// calendarText returns the name of value if it is a valid time.Month or
+// time.Weekday, e.g. "January". Unlike other Stringers, these are named
+// regardless of the Stringers option.
+func calendarText(value reflect.Value) (string, bool) {
+	switch value.Type() {
+	case monthType:
+		if m := time.Month(value.Int()); m >= time.January && m <= time.December {
+			return m.String(), true
+		}
+	case weekdayType:
+		if d := time.Weekday(value.Int()); d >= time.Sunday && d <= time.Saturday {
+			return d.String(), true
+		}
 	}
-	return
+	return "", false
 }
 
-var timeType = reflect.TypeOf(time.Time{})
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	monthType    = reflect.TypeOf(time.Month(0))
+	weekdayType  = reflect.TypeOf(time.Weekday(0))
+)
@@ -0,0 +1,153 @@
+package render
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// TokenKind classifies the text of a Token.
+type TokenKind int
+
+const (
+	// TokenPunct is punctuation: brackets, separators, and whitespace.
+	TokenPunct TokenKind = iota
+	// TokenType is a type name, e.g. "render.T" or "(*int)".
+	TokenType
+	// TokenKey is a struct field name, or a slice index (see ShowIndices).
+	// Map keys are values, and are tokenized as such.
+	TokenKey
+	// TokenString is a quoted string.
+	TokenString
+	// TokenNumber is a number, including pointer addresses.
+	TokenNumber
+	// TokenLiteral is other value text: booleans, nil, times, and the text of
+	// values such as enums, durations, and IP addresses.
+	TokenLiteral
+	// TokenMarker is text added by the renderer rather than taken from the
+	// value, such as recursion markers and comments.
+	TokenMarker
+	// TokenTruncated marks where output was left out because a limit was
+	// reached, such as MaxTotalBytes, MaxNodes, or MaxSliceLen.
+	TokenTruncated
+)
+
+// Token is a piece of rendered output.
+type Token struct {
+	Kind TokenKind
+	Text string
+}
+
+// RenderTokens renders v like Render, split into tokens. Joining the tokens'
+// texts yields the output of Render.
+func RenderTokens(v any) []Token {
+	return RenderTokensWith(v, RenderOptions{})
+}
+
+// RenderTokensWith is like RenderTokens, but allows the output to be
+// customized through opts, as in RenderWith.
+func RenderTokensWith(v any, opts RenderOptions) []Token {
+	r := renderer{opts: &opts, tokens: true}
+	r.run(reflect.ValueOf(v))
+	return r.tokenize()
+}
+
+// span is the text of a token of kind kind, written from start to end.
+type span struct {
+	start, end int
+	kind       TokenKind
+}
+
+// emit records the text written from start on as a token of kind kind, if
+// tokens are recorded.
+func (r *renderer) emit(kind TokenKind, start int) {
+	if r.tokens && r.buf.Len() > start {
+		r.spans = append(r.spans, span{start, r.buf.Len(), kind})
+	}
+}
+
+// writeToken writes text as a token of kind kind.
+func (r *renderer) writeToken(kind TokenKind, text string) {
+	start := r.buf.Len()
+	r.buf.WriteString(text)
+	r.emit(kind, start)
+}
+
+// cutSpans drops the recorded tokens past n, where the output is cut. As
+// tokens are recorded once written, their ends are in increasing order.
+func (r *renderer) cutSpans(n int) {
+	kept := len(r.spans)
+	for kept > 0 && r.spans[kept-1].end > n {
+		kept--
+	}
+	for _, sp := range r.spans[kept:] {
+		if sp.start < n {
+			sp.end = n
+			r.spans = append(r.spans[:kept], sp)
+			kept++
+		}
+	}
+	r.spans = r.spans[:kept]
+}
+
+// takeSpans removes the recorded tokens from start on, and returns them with
+// positions relative to start.
+func (r *renderer) takeSpans(start int) []span {
+	i := len(r.spans)
+	for i > 0 && r.spans[i-1].start >= start {
+		i--
+	}
+	taken := append([]span(nil), r.spans[i:]...)
+	for j := range taken {
+		taken[j].start -= start
+		taken[j].end -= start
+	}
+	r.spans = r.spans[:i]
+	return taken
+}
+
+// putSpans records the tokens taken by takeSpans, at positions relative to
+// start.
+func (r *renderer) putSpans(spans []span, start int) {
+	for _, sp := range spans {
+		r.spans = append(r.spans, span{sp.start + start, sp.end + start, sp.kind})
+	}
+}
+
+// tokenize splits the output into tokens, using the recorded spans. The text
+// between them is punctuation, which is split so that each bracket is a token
+// of its own.
+func (r *renderer) tokenize() []Token {
+	out := r.buf.String()
+	spans := r.spans
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var tokens []Token
+	pos := 0
+	for _, sp := range spans {
+		if sp.start < pos {
+			// Nested in the previous token.
+			continue
+		}
+		tokens = appendPunct(tokens, out[pos:sp.start])
+		tokens = append(tokens, Token{sp.kind, out[sp.start:sp.end]})
+		pos = sp.end
+	}
+	return appendPunct(tokens, out[pos:])
+}
+
+// appendPunct appends the punctuation text to tokens.
+func appendPunct(tokens []Token, text string) []Token {
+	for text != "" {
+		n := strings.IndexAny(text, "{}()[]")
+		switch {
+		case n < 0:
+			n = len(text)
+		case n == 0:
+			n = 1
+		}
+		tokens = append(tokens, Token{TokenPunct, text[:n]})
+		text = text[n:]
+	}
+	return tokens
+}
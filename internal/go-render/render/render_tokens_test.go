@@ -0,0 +1,126 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// assertTokensLike checks the tokens of v, written as "kind:text" pairs.
+func assertTokensLike(t *testing.T, name string, v any, exp []string) {
+	t.Helper()
+
+	tokens := RenderTokens(v)
+	act := make([]string, len(tokens))
+	var text strings.Builder
+	for i, tok := range tokens {
+		act[i] = tokenKindNames[tok.Kind] + ":" + tok.Text
+		text.WriteString(tok.Text)
+	}
+	if !reflect.DeepEqual(act, exp) {
+		t.Errorf("[%s] tokens did not match expectations:\nExpected: %q\nActual  : %q", name, exp, act)
+	}
+	if r := Render(v); text.String() != r {
+		t.Errorf("[%s] tokens don't join to the rendered text:\nExpected: %s\nActual  : %s", name, r, text.String())
+	}
+}
+
+var tokenKindNames = map[TokenKind]string{
+	TokenPunct:     "punct",
+	TokenType:      "type",
+	TokenKey:       "key",
+	TokenString:    "string",
+	TokenNumber:    "number",
+	TokenLiteral:   "literal",
+	TokenMarker:    "marker",
+	TokenTruncated: "truncated",
+}
+
+func TestRenderTokenKinds(t *testing.T) {
+	type point struct {
+		X, Y int
+		Name string
+		Next *point
+	}
+	p := &point{X: 1, Y: -2, Name: "a"}
+	p.Next = p
+
+	assertTokensLike(t, "Struct", p, []string{
+		"type:(*render.point)", "punct:{",
+		"key:X", "punct::", "number:1", "punct:, ",
+		"key:Y", "punct::", "number:-2", "punct:, ",
+		"key:Name", "punct::", `string:"a"`, "punct:, ",
+		"key:Next", "punct::", "marker:<REC(*render.point)>",
+		"punct:}",
+	})
+	assertTokensLike(t, "Map", map[string]any{"b": true, "a": nil, "c": 1.5}, []string{
+		"type:map[string]any", "punct:{",
+		`string:"a"`, "punct::", "type:any", "punct:(", "literal:nil", "punct:)", "punct:, ",
+		`string:"b"`, "punct::", "literal:true", "punct:, ",
+		`string:"c"`, "punct::", "number:1.5",
+		"punct:}",
+	})
+	assertTokensLike(t, "Scalar", uint8(3), []string{"number:3"})
+	assertTokensLike(t, "Nil", nil, []string{"literal:nil"})
+}
+
+func TestRenderTokenTexts(t *testing.T) {
+	type inner struct {
+		B []byte
+		C chan int
+		F func()
+	}
+	for _, v := range []any{
+		[]any{1, "x", nil, []int{1, 1, 1}, complex(1, 2)},
+		map[[2]string]bool{{"a", "b"}: true},
+		&inner{B: []byte("hi")},
+		[3]*int{},
+		reflect.ValueOf(3),
+	} {
+		var text strings.Builder
+		for _, tok := range RenderTokens(v) {
+			text.WriteString(tok.Text)
+		}
+		if exp := Render(v); text.String() != exp {
+			t.Errorf("tokens don't join to the rendered text:\nExpected: %s\nActual  : %s", exp, text.String())
+		}
+	}
+}
+
+func TestRenderTokensTruncated(t *testing.T) {
+	v := map[string][]int{"a": {1, 2, 3, 4}, "b": {5}}
+
+	for _, tc := range []struct {
+		name string
+		opts RenderOptions
+		exp  string
+	}{
+		{"Total bytes", RenderOptions{MaxTotalBytes: 20}, "...<truncated>"},
+		{"Nodes", RenderOptions{MaxNodes: 3}, "...<node limit>"},
+		{"Slice length", RenderOptions{MaxSliceLen: 2}, "...(+2 more of 4)"},
+		{"Map length", RenderOptions{MaxMapLen: 1}, "...(+1 more of 2)"},
+		{"Depth", RenderOptions{MaxDepth: 1}, "..."},
+	} {
+		var found []string
+		var text strings.Builder
+		tokens := RenderTokensWith(v, tc.opts)
+		for _, tok := range tokens {
+			if tok.Kind == TokenTruncated {
+				found = append(found, tok.Text)
+			}
+			text.WriteString(tok.Text)
+		}
+		if len(found) == 0 || found[0] != tc.exp {
+			t.Errorf("[%s] expected a %q truncation token, got %q", tc.name, tc.exp, found)
+		}
+		if exp := RenderWith(v, tc.opts); text.String() != exp {
+			t.Errorf("[%s] tokens don't join to the rendered text:\nExpected: %s\nActual  : %s", tc.name, exp, text.String())
+		}
+	}
+
+	for _, tok := range RenderTokens(v) {
+		if tok.Kind == TokenTruncated {
+			t.Errorf("unexpected truncation token %q without limits", tok.Text)
+		}
+	}
+}
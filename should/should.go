@@ -27,6 +27,7 @@ var (
 	EndWith                = assertions.ShouldEndWith
 	Equal                  = assertions.ShouldEqual
 	EqualJSON              = assertions.ShouldEqualJSON
+	EqualRendered          = assertions.ShouldEqualRendered
 	EqualTrimSpace         = assertions.ShouldEqualTrimSpace
 	EqualWithout           = assertions.ShouldEqualWithout
 	HappenAfter            = assertions.ShouldHappenAfter